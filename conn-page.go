@@ -122,7 +122,7 @@ func NewConnPage(c *Context) *ConnPage {
       cp.selector.SelectItem(-1)
     })
 
-  handler := GetFormKeyHandler(c.app, cp.form, 8, 2)
+  handler := GetFormKeyHandler(c.app, cp.form, cp.form.GetFormItemCount(), 2)
   cp.form.
     SetInputCapture(func (event *tcell.EventKey) *tcell.EventKey {
       if cp.showingMsg {
@@ -163,8 +163,13 @@ func (cp *ConnPage) mountLayout() {
     AddInputField("User", "", 0, nil, nil).
     AddInputField("Password", "", 0, nil, nil).
     AddInputField("Database", "", 0, nil, nil).
+    AddInputField("Stmt. Timeout", "", 0, nil, nil).
+    AddInputField("Lock Timeout", "", 0, nil, nil).
+    AddInputField("Idle Tx Timeout", "", 0, nil, nil).
     AddCheckbox("Enable SSL", false, nil).
-    AddCheckbox("Default", false, nil)
+    AddCheckbox("Default", false, nil).
+    AddCheckbox("Protected", false, nil).
+    AddCheckbox("Read only", false, nil)
 
   cp.form.
     SetFieldBackgroundColor(tcell.NewRGBColor(100, 50, 200)).
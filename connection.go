@@ -17,6 +17,17 @@ type Connection struct {
   Db   string `json:"db"`
   Ssl  bool   `json:"ssl"`
   IsDefault  bool `json:"default"`
+
+  // Postgres intervals, like 30s or 5min, empty to keep the server default
+  StatementTimeout string `json:"statement_timeout,omitempty"`
+  LockTimeout      string `json:"lock_timeout,omitempty"`
+  IdleTimeout      string `json:"idle_in_transaction_session_timeout,omitempty"`
+
+  // Destructive statements always ask for a confirmation
+  Protected bool `json:"protected,omitempty"`
+
+  // Sessions start with default_transaction_read_only and writes are refused
+  ReadOnly bool `json:"read_only,omitempty"`
 }
 
 type Connections []Connection
@@ -53,8 +64,13 @@ func (c* Connection) ReadFromForm(form *tview.Form) {
   c.User = GetFormInputValue(form, 3)
   c.Pass = GetFormInputValue(form, 4)
   c.Db   = GetFormInputValue(form, 5)
-  c.Ssl  = GetFormCheckValue(form, 6)
-  c.IsDefault = GetFormCheckValue(form, 7)
+  c.StatementTimeout = GetFormInputValue(form, 6)
+  c.LockTimeout      = GetFormInputValue(form, 7)
+  c.IdleTimeout      = GetFormInputValue(form, 8)
+  c.Ssl  = GetFormCheckValue(form, 9)
+  c.IsDefault = GetFormCheckValue(form, 10)
+  c.Protected = GetFormCheckValue(form, 11)
+  c.ReadOnly = GetFormCheckValue(form, 12)
 }
 
 func (c* Connection) WriteToForm(form *tview.Form) {
@@ -64,12 +80,56 @@ func (c* Connection) WriteToForm(form *tview.Form) {
   SetFormInputValue(form, 3, c.User)
   SetFormInputValue(form, 4, c.Pass)
   SetFormInputValue(form, 5, c.Db)
-  SetFormCheckValue(form, 6, c.Ssl)
-  SetFormCheckValue(form, 7, c.IsDefault)
+  SetFormInputValue(form, 6, c.StatementTimeout)
+  SetFormInputValue(form, 7, c.LockTimeout)
+  SetFormInputValue(form, 8, c.IdleTimeout)
+  SetFormCheckValue(form, 9, c.Ssl)
+  SetFormCheckValue(form, 10, c.IsDefault)
+  SetFormCheckValue(form, 11, c.Protected)
+  SetFormCheckValue(form, 12, c.ReadOnly)
+}
+
+func (c *Connection) DbInfo() *DBInfo {
+  return &DBInfo{
+    conn: c.Name,
+    name: c.Db,
+    user: c.User,
+    pass: c.Pass,
+    host: c.Host,
+    port: c.Port,
+    ssl:  c.Ssl,
+
+    statementTimeout: c.StatementTimeout,
+    lockTimeout:      c.LockTimeout,
+    idleTimeout:      c.IdleTimeout,
+
+    protected: c.Protected,
+    readOnly:  c.ReadOnly,
+  }
 }
 
 type Config struct {
   Connections []Connection `json:"connections"`
+
+  PageSize int `json:"page_size,omitempty"`
+  MaxRows  int `json:"max_rows,omitempty"`
+
+  // Seconds between pings of the session, 0 disables them
+  Keepalive int `json:"keepalive,omitempty"`
+}
+
+func (c *Config) GetPageSize() int {
+  if c.PageSize > 0 {
+    return c.PageSize
+  }
+  return DEFAULT_PAGE_SIZE
+}
+
+func (c *Config) GetMaxRows() int {
+  if c.MaxRows > 0 {
+    return c.MaxRows
+  }
+  return DEFAULT_MAX_ROWS
 }
 
 func ReadConfigFile() (*Config, error) {
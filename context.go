@@ -5,6 +5,10 @@ import (
   "github.com/gdamore/tcell"
 
 	"database/sql"
+  "context"
+  "fmt"
+  "sync"
+  "time"
 
 	_ "github.com/lib/pq"
 )
@@ -20,16 +24,32 @@ const (
   DATABASE
   COLUMNS
   INDEXES
+
+  PLAN
+  NOTIFICATIONS
+  JOBS
+  HISTORY
+  SEARCH
 )
 
 type SelectedMenu byte
 const (
   RUN_MENU     SelectedMenu = iota
   STRUCT_MENU
+  PLAN_MENU
+  NOTIFY_MENU
+  JOBS_MENU
+  HISTORY_MENU
 )
 
 type Context struct {
   db *sql.DB
+  session *Session // connection used by the Execute page
+  sessionMutex sync.Mutex // the connection watch reads the session too
+  monitor *Monitor // LISTEN connection
+  watch   *ConnWatch
+  jobs    *JobList
+  history *QueryHistory
 
   app *tview.Application
   info *DBInfo
@@ -43,20 +63,138 @@ type Context struct {
   initPage   *InitPage
   runPage    *RunPage
   structPage *StructPage
+  planPage   *PlanPage
+  notifyPage *NotifyPage
+  jobsPage   *JobsPage
+  historyPage *HistoryPage
 
   loading *Loading
+
+  quitting bool // the open transaction is being ended before stopping
 }
 
 func (c *Context) Finish() {
+  if c.runPage != nil {
+    c.runPage.CancelQuery()
+  }
+
   if c.loading != nil && c.loading.waiting {
     c.loading.Close()
   }
 
+  if c.monitor != nil {
+    c.monitor.Close()
+  }
+
+  if c.watch != nil {
+    c.watch.Close()
+  }
+
+  if c.jobs != nil {
+    for _, job := range c.jobs.Jobs() {
+      c.jobs.Cancel(job.id)
+    }
+  }
+
   if c.app != nil {
     c.app.Stop()
   }
 }
 
+// Asks what to do with an open transaction before stopping the application.
+// Quitting again while asking, or while the transaction ends, stops it right away.
+func (c *Context) Quit() {
+  if c.quitting || c.session == nil || c.session.State() == TX_IDLE || c.mainPages.HasPage("Confirm") {
+    c.Finish()
+    return
+  }
+
+  text := "The transaction is still open (" + c.session.State().String() + ").\nWhat should be done with it?"
+
+  c.Confirm(text, []string{"Commit", "Rollback", "Cancel"}, func (label string) {
+    if label != "Commit" && label != "Rollback" {
+      return
+    }
+
+    if c.runPage != nil {
+      c.runPage.CancelQuery()
+    }
+
+    // Off the UI, a slow COMMIT or a dead connection mustn't freeze it
+    c.quitting = true
+    c.runPage.SetStatus(label + " before quitting, quit again to stop right away...")
+    session := c.session
+
+    go func () {
+      var err error
+      if label == "Commit" {
+        err = session.Commit(context.Background())
+      } else {
+        err = session.Rollback(context.Background())
+      }
+
+      c.Enqueue(func () {
+        if err != nil && label == "Commit" {
+          c.quitting = false
+          c.runPage.UpdateTxState(c)
+          c.runPage.SetStatus("The commit failed, still running: " + err.Error())
+          return
+        }
+
+        c.Finish()
+      })
+    }()
+  })
+}
+
+// Shows a modal over the current page, done receives the label of the pressed
+// button, or an empty string when it is closed with Escape
+func (c *Context) Confirm(text string, buttons []string, done func(string)) {
+  focused := c.app.GetFocus()
+
+  modal := tview.NewModal().
+    SetText(text).
+    AddButtons(buttons).
+    SetDoneFunc(func (index int, label string) {
+      c.mainPages.RemovePage("Confirm")
+      c.app.SetFocus(focused)
+      done(label)
+    })
+
+  c.mainPages.AddPage("Confirm", modal, false, true)
+  c.app.SetFocus(modal)
+}
+
+const menuItems = ` ["0"][yellow] [::bu]E[::-]xecute [white][""] | ["1"][yellow] [::bu]S[::-]tructure [white][""] | ["3"][yellow] [::bu]P[::-]lan [white][""] | ["4"][yellow] [::bu]N[::-]otifications [white][""] | ["5"][yellow] [::bu]J[::-]obs [white][""] | ["6"][yellow] [::bu]H[::-]istory [white][""] | ["2"][yellow] [::bu]Q[::-]uit [white][""]`
+
+// Writes the menu items followed by the state of the connection
+func (c *Context) RefreshMenuBar() {
+  text := menuItems
+
+  if c.jobs != nil {
+    if running := c.jobs.Running(); running > 0 {
+      text += fmt.Sprintf("   [yellow]%d job(s) running[white]", running)
+    }
+  }
+
+  if c.watch != nil {
+    switch c.watch.State() {
+    case CONN_UP:
+      text += "   [green]connected[white]"
+    case CONN_LOST:
+      text += "   [red]" + c.watch.String() + "[white]"
+    default:
+      text += "   [yellow]" + c.watch.String() + "[white]"
+    }
+  }
+
+  if c.info != nil && c.info.readOnly {
+    text += "   [black:red] READ-ONLY [-:-]"
+  }
+
+  c.menuBar.SetText(text)
+}
+
 func (c *Context) FocusMenu() {
   if c.menuBar != nil {
     c.app.SetFocus(c.menuBar)
@@ -69,7 +207,7 @@ func (c *Context) SetFocus(p tview.Primitive) {
 
 func (c *Context) HandleMenuKeyInput(event *tcell.EventKey) {
   if event.Rune() == 'q' {
-    c.Finish()
+    c.Quit()
 
   } else if event.Rune() == 'e' && c.selectedMenu != RUN_MENU {
     c.menuBar.Highlight("0")
@@ -79,6 +217,22 @@ func (c *Context) HandleMenuKeyInput(event *tcell.EventKey) {
     c.menuBar.Highlight("1")
     c.selectedMenu = STRUCT_MENU
 
+  } else if event.Rune() == 'p' && c.selectedMenu != PLAN_MENU {
+    c.menuBar.Highlight("3")
+    c.selectedMenu = PLAN_MENU
+
+  } else if event.Rune() == 'n' && c.selectedMenu != NOTIFY_MENU {
+    c.menuBar.Highlight("4")
+    c.selectedMenu = NOTIFY_MENU
+
+  } else if event.Rune() == 'j' && c.selectedMenu != JOBS_MENU {
+    c.menuBar.Highlight("5")
+    c.selectedMenu = JOBS_MENU
+
+  } else if event.Rune() == 'h' && c.selectedMenu != HISTORY_MENU {
+    c.menuBar.Highlight("6")
+    c.selectedMenu = HISTORY_MENU
+
   } else if c.selectedMenu == RUN_MENU {
     if event.Key() == tcell.KeyCtrlE || event.Rune() == '0' {
       c.runPage.SetCompType(EDITOR)
@@ -102,9 +256,135 @@ func (c *Context) HandleMenuKeyInput(event *tcell.EventKey) {
       c.structPage.SetCompType(INDEXES)
       c.SetFocus(c.structPage.indexesTable)
     }
+
+  } else if c.selectedMenu == PLAN_MENU {
+    if event.Key() == tcell.KeyCtrlT || event.Rune() == 't' {
+      c.planPage.SetCompType(PLAN)
+      c.SetFocus(c.planPage.tree)
+    }
+
+  } else if c.selectedMenu == NOTIFY_MENU {
+    if event.Key() == tcell.KeyCtrlT || event.Rune() == 't' {
+      c.notifyPage.SetCompType(NOTIFICATIONS)
+      c.SetFocus(c.notifyPage.table)
+    }
+
+  } else if c.selectedMenu == JOBS_MENU {
+    if event.Key() == tcell.KeyCtrlT || event.Rune() == 't' {
+      c.jobsPage.SetCompType(JOBS)
+      c.SetFocus(c.jobsPage.table)
+    }
+
+  } else if c.selectedMenu == HISTORY_MENU {
+    if event.Key() == tcell.KeyCtrlT || event.Rune() == 't' {
+      c.historyPage.SetCompType(HISTORY)
+      c.SetFocus(c.historyPage.table)
+
+    } else if event.Rune() == '/' {
+      c.historyPage.SetCompType(SEARCH)
+      c.SetFocus(c.historyPage.search)
+    }
   }
 }
 
+// Switches to the Plan page with the focus on the plan tree
+func (c *Context) ShowPlan() {
+  c.menuBar.Highlight("3")
+  c.selectedMenu = PLAN_MENU
+
+  c.planPage.SetCompType(PLAN)
+  c.SetFocus(c.planPage.tree)
+}
+
 func (c *Context) Enqueue(fn func ()) {
   c.app.QueueUpdateDraw(fn)
 }
+
+// Session of the Execute page, for the goroutines out of the UI
+func (c *Context) Session() *Session {
+  c.sessionMutex.Lock()
+  defer c.sessionMutex.Unlock()
+
+  return c.session
+}
+
+// Replaces the session, while the connection watch may be reading it
+func (c *Context) SetSession(session *Session) {
+  c.sessionMutex.Lock()
+  defer c.sessionMutex.Unlock()
+
+  c.session = session
+}
+
+// Watches the session for a lost connection, called once connected
+func (c *Context) WatchConnection() {
+  var watch *ConnWatch
+  db := c.db
+
+  watch = NewConnWatch(
+    func (ctx context.Context) error {
+      return c.Reconnect(ctx, db, watch)
+    },
+    func (ctx context.Context) error {
+      session := c.Session()
+      if session == nil {
+        return nil
+      }
+      return session.Ping(ctx)
+    },
+    func () {
+      c.Enqueue(c.RefreshMenuBar)
+    },
+    func () {
+      c.Enqueue(func () { c.runPage.Reconnected(c) })
+    })
+
+  c.watch = watch
+  c.watch.SetKeepalive(time.Duration(c.config.Keepalive) * time.Second)
+}
+
+// Opens a new session once the server answers again. The pool drops its
+// broken connections by itself, the session has to be replaced.
+func (c *Context) Reconnect(ctx context.Context, db *sql.DB, watch *ConnWatch) error {
+  err := db.PingContext(ctx)
+
+  if err != nil {
+    return err
+  }
+
+  session, err := OpenSessionContext(ctx, db)
+
+  if err != nil {
+    return err
+  }
+
+  c.Enqueue(func () {
+    // The connection was left, or replaced, while the session was opened
+    if watch.Closed() {
+      go session.Close()
+      return
+    }
+
+    old := c.session
+    c.SetSession(session)
+
+    if old != nil {
+      c.runPage.lostTx = old.State() != TX_IDLE
+      go old.Close()
+    }
+  })
+
+  return nil
+}
+
+// Starts reconnecting when the error means the connection was lost
+func (c *Context) CheckConnection(err error) bool {
+  if c.watch == nil || !IsConnectionError(err) {
+    return false
+  }
+
+  c.watch.Lost(err)
+  c.RefreshMenuBar()
+
+  return true
+}
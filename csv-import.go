@@ -0,0 +1,239 @@
+package main
+
+import (
+  "context"
+  "database/sql"
+  "encoding/csv"
+  "errors"
+  "io"
+  "os"
+  "strings"
+
+	"github.com/lib/pq"
+)
+
+// Ways a CSV field can be read as NULL
+var csvNullOptions = []string{"empty field", `\N`, "NULL", "never"}
+
+type CsvImport struct {
+  path string
+  name string // of the table, as written
+
+  schema, table string // as found by Resolve, unquoted
+
+  header    bool
+  delimiter rune
+  null      int // index in csvNullOptions
+
+  columns []string // table columns
+  mapping []int    // csv column of each table column, -1 to skip it
+}
+
+func NewCsvImport(path, table string) *CsvImport {
+  return &CsvImport{path: path, name: table, header: true, delimiter: ','}
+}
+
+// Finds the table as the server reads its name, so the columns and the COPY
+// use the same one, with its schema and its columns in their order
+func (imp *CsvImport) Resolve(ctx context.Context, db Queryer) error {
+  rows, err := db.QueryContext(ctx,
+    `SELECT n.nspname, c.relname, a.attname
+     FROM pg_catalog.pg_class c
+     INNER JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+     LEFT JOIN pg_catalog.pg_attribute a
+       ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+     WHERE c.oid = $1::regclass
+     ORDER BY a.attnum`, imp.name)
+
+  if err != nil {
+    return err
+  }
+  defer rows.Close()
+
+  imp.columns = []string{}
+
+  for rows.Next() {
+    column := sql.NullString{}
+    if err := rows.Scan(&imp.schema, &imp.table, &column); err != nil {
+      return err
+    }
+
+    if column.Valid {
+      imp.columns = append(imp.columns, column.String)
+    }
+  }
+
+  return rows.Err()
+}
+
+func (imp *CsvImport) reader(file io.Reader) *csv.Reader {
+  reader := csv.NewReader(file)
+  reader.Comma = imp.delimiter
+  reader.FieldsPerRecord = -1
+  reader.LazyQuotes = true
+  reader.ReuseRecord = true
+  return reader
+}
+
+// First rows of the file, the header included
+func (imp *CsvImport) Preview(rows int) ([][]string, error) {
+  path, err := ExpandHomeDir(imp.path)
+  if err != nil {
+    return nil, err
+  }
+
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer file.Close()
+
+  reader := imp.reader(file)
+  reader.ReuseRecord = false
+
+  records := [][]string{}
+  for len(records) < rows {
+    record, err := reader.Read()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return records, err
+    }
+    records = append(records, record)
+  }
+
+  return records, nil
+}
+
+// Maps the table columns to the csv columns with the same name, or to the
+// ones in the same position when there is no header
+func (imp *CsvImport) AutoMap(first []string) {
+  imp.mapping = make([]int, len(imp.columns))
+
+  for i, column := range imp.columns {
+    imp.mapping[i] = -1
+
+    if !imp.header {
+      if i < len(first) {
+        imp.mapping[i] = i
+      }
+      continue
+    }
+
+    for j, name := range first {
+      if strings.EqualFold(strings.TrimSpace(name), column) {
+        imp.mapping[i] = j
+        break
+      }
+    }
+  }
+}
+
+func (imp *CsvImport) isNull(value string) bool {
+  switch csvNullOptions[imp.null] {
+  case "empty field":
+    return value == ""
+  case "never":
+    return false
+  }
+  return value == csvNullOptions[imp.null]
+}
+
+// Streams the file into the table with COPY FROM STDIN, inside a transaction
+// that is rolled back on any error. progress receives the rows copied so far.
+func (imp *CsvImport) Copy(ctx context.Context, db *sql.DB, progress func(int64)) (int64, error) {
+  columns := []string{}
+  fields := []int{}
+
+  for i, j := range imp.mapping {
+    if j >= 0 {
+      columns = append(columns, imp.columns[i])
+      fields = append(fields, j)
+    }
+  }
+
+  if len(columns) == 0 {
+    return 0, errors.New("No column to be imported.")
+  }
+
+  path, err := ExpandHomeDir(imp.path)
+  if err != nil {
+    return 0, err
+  }
+
+  file, err := os.Open(path)
+  if err != nil {
+    return 0, err
+  }
+  defer file.Close()
+
+  tx, err := db.BeginTx(ctx, nil)
+  if err != nil {
+    return 0, err
+  }
+
+  fail := func (err error) (int64, error) {
+    tx.Rollback()
+    return 0, err
+  }
+
+  stmt, err := tx.Prepare(pq.CopyInSchema(imp.schema, imp.table, columns...))
+  if err != nil {
+    return fail(err)
+  }
+
+  reader := imp.reader(file)
+  values := make([]interface{}, len(fields))
+
+  if imp.header {
+    if _, err := reader.Read(); err != nil && err != io.EOF {
+      return fail(err)
+    }
+  }
+
+  var rows int64
+
+  for {
+    record, err := reader.Read()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return fail(err)
+    }
+
+    for i, j := range fields {
+      if j >= len(record) || imp.isNull(record[j]) {
+        values[i] = nil
+      } else {
+        values[i] = record[j]
+      }
+    }
+
+    if _, err := stmt.ExecContext(ctx, values...); err != nil {
+      return fail(err)
+    }
+
+    rows++
+    if rows % 1000 == 0 {
+      progress(rows)
+    }
+  }
+
+  // Flushes the copy, constraint errors of the last rows show up here
+  if _, err := stmt.ExecContext(ctx); err != nil {
+    return fail(err)
+  }
+
+  if err := stmt.Close(); err != nil {
+    return fail(err)
+  }
+
+  if err := tx.Commit(); err != nil {
+    return 0, err
+  }
+
+  progress(rows)
+  return rows, nil
+}
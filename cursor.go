@@ -0,0 +1,208 @@
+package main
+
+import (
+  "context"
+  "fmt"
+  "sync/atomic"
+  "time"
+)
+
+const (
+  DEFAULT_PAGE_SIZE = 500
+  DEFAULT_MAX_ROWS  = 100000
+
+  // An implicit cursor not fetched from for this long is closed, so its
+  // transaction doesn't hold the snapshot and the vacuum horizon forever
+  CURSOR_IDLE_TIME = 2 * time.Minute
+)
+
+// Numbers the cursors, which the sessions of the jobs open on their goroutines
+var cursorCount atomic.Int64
+
+// Server side cursor used to fetch a result set page by page
+type ResultCursor struct {
+  session *Session
+  name    string
+
+  // The cursor opened its own transaction, since cursors only live inside one
+  implicit bool
+  idle     *time.Timer
+
+  pageSize int
+  maxRows  int // 0 means no limit
+
+  // Written with the conn in use and the state mutex of the session held
+  fetched   int
+  done      bool
+  capped    bool
+  exhausted bool
+}
+
+// Declares a cursor for the query and fetches its first page, with the conn
+// of the session in use
+func OpenCursor(ctx context.Context, session *Session, query string, pageSize, maxRows int, args ...interface{}) (*ResultCursor, QueryResult) {
+  rc := &ResultCursor{
+    session: session,
+    name: fmt.Sprintf("postdigress_cursor_%d", cursorCount.Add(1)),
+    pageSize: Max(1, pageSize),
+    maxRows: maxRows,
+  }
+
+  if session.State() == TX_IDLE {
+    _, err := session.conn.ExecContext(ctx, "BEGIN")
+
+    if err != nil {
+      return nil, ErrorResult(err)
+    }
+
+    rc.implicit = true
+
+    // The idle timer closes it instead of the server killing the session
+    _, err = session.conn.ExecContext(ctx, "SET LOCAL idle_in_transaction_session_timeout = 0")
+
+    if err != nil {
+      rc.close()
+      return nil, ErrorResult(err)
+    }
+  }
+
+  declare := "DECLARE " + rc.name + " NO SCROLL CURSOR FOR " + TrimStatement(query)
+  _, err := session.conn.ExecContext(ctx, declare, args...)
+
+  if err != nil {
+    rc.close()
+    return nil, ErrorResult(err)
+  }
+
+  return rc, rc.fetch(ctx)
+}
+
+func (rc *ResultCursor) Fetch(ctx context.Context) QueryResult {
+  var result QueryResult
+  rc.session.use(ctx, func (ctx context.Context) {
+    result = rc.fetch(ctx)
+  })
+  return result
+}
+
+func (rc *ResultCursor) fetch(ctx context.Context) QueryResult {
+  if rc.done {
+    return QueryResult{columns: []string{}, values: [][]string{}, err: nil}
+  }
+
+  size := rc.pageSize
+  if rc.maxRows > 0 {
+    size = Min(size, rc.maxRows - rc.fetched)
+  }
+
+  query := fmt.Sprintf("FETCH FORWARD %d FROM %s", size, rc.name)
+  rows, err := rc.session.conn.QueryContext(ctx, query)
+
+  if err != nil {
+    rc.fail()
+    return ErrorResult(err)
+  }
+
+  result := ScanRows(rows)
+  rows.Close()
+
+  if result.err != nil {
+    rc.fail()
+    return result
+  }
+
+  rc.session.stateMutex.Lock()
+  rc.fetched += len(result.values)
+
+  if len(result.values) < size {
+    rc.exhausted = true
+  } else if rc.maxRows > 0 && rc.fetched >= rc.maxRows {
+    rc.capped = true
+  }
+  rc.session.stateMutex.Unlock()
+
+  if rc.exhausted || rc.capped {
+    rc.close()
+  } else if rc.implicit {
+    if rc.idle != nil {
+      rc.idle.Stop()
+    }
+    rc.idle = time.AfterFunc(CURSOR_IDLE_TIME, rc.Close)
+  }
+
+  return result
+}
+
+// An error inside the user's transaction aborts it
+func (rc *ResultCursor) fail() {
+  rc.session.stateMutex.Lock()
+  if !rc.implicit && rc.session.state == TX_ACTIVE {
+    rc.session.state = TX_FAILED
+  }
+  rc.session.stateMutex.Unlock()
+
+  rc.close()
+}
+
+func (rc *ResultCursor) Close() {
+  rc.session.use(context.Background(), func (ctx context.Context) {
+    rc.close()
+  })
+}
+
+func (rc *ResultCursor) close() {
+  if rc.done {
+    return
+  }
+
+  rc.session.stateMutex.Lock()
+  rc.done = true
+  rc.session.stateMutex.Unlock()
+
+  if rc.idle != nil {
+    rc.idle.Stop()
+  }
+
+  ctx := context.Background()
+
+  if rc.implicit {
+    // Commit keeps the same effects a plain execution of the query would
+    // have, the server rolls back instead if the transaction failed
+    rc.session.conn.ExecContext(ctx, "COMMIT")
+  } else {
+    rc.session.conn.ExecContext(ctx, "CLOSE " + rc.name)
+  }
+}
+
+func (rc *ResultCursor) Done() bool {
+  rc.session.stateMutex.Lock()
+  defer rc.session.stateMutex.Unlock()
+
+  return rc.done
+}
+
+func (rc *ResultCursor) Exhausted() bool {
+  rc.session.stateMutex.Lock()
+  defer rc.session.stateMutex.Unlock()
+
+  return rc.exhausted
+}
+
+func (rc *ResultCursor) Status() string {
+  rc.session.stateMutex.Lock()
+  defer rc.session.stateMutex.Unlock()
+
+  if rc.capped {
+    return fmt.Sprintf("%d rows, row cap reached", rc.fetched)
+  }
+
+  if !rc.done {
+    return fmt.Sprintf("%d rows fetched, scroll for more", rc.fetched)
+  }
+
+  if !rc.exhausted {
+    return fmt.Sprintf("first %d rows, cursor closed", rc.fetched)
+  }
+
+  return fmt.Sprintf("%d rows", rc.fetched)
+}
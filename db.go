@@ -2,39 +2,177 @@ package main
 
 import (
 	"database/sql"
+  "context"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
   "fmt"
 
   "errors"
   "time"
   "reflect"
+  "strings"
+  "math"
+  "encoding/hex"
 )
 
 type QueryResult struct {
   columns []string
+  types   []ColumnInfo
   values  [][]string
+  nulls   [][]bool
   err error
+
+  affected int64 // rows touched by an exec, -1 when unknown
+}
+
+// Column metadata reported by the driver
+type ColumnInfo struct {
+  name   string
+  dbType string // like INT4, VARCHAR or _TEXT for arrays
+
+  length           int64 // -1 when unknown
+  precision, scale int64 // -1 when unknown
+
+  nullable, nullableKnown bool
+}
+
+func NewColumnInfo(ct *sql.ColumnType) ColumnInfo {
+  info := ColumnInfo{
+    name: ct.Name(),
+    dbType: ct.DatabaseTypeName(),
+    length: -1,
+    precision: -1,
+    scale: -1,
+  }
+
+  // Unconstrained types come with garbage or "infinite" sizes
+  if length, ok := ct.Length(); ok && length > 0 && length < math.MaxInt32 {
+    info.length = length
+  }
+
+  if precision, scale, ok := ct.DecimalSize(); ok && precision <= 1000 && scale <= precision {
+    info.precision, info.scale = precision, scale
+  }
+
+  info.nullable, info.nullableKnown = ct.Nullable()
+
+  return info
+}
+
+// Type name as shown under the column headers, e.g. varchar(20) or int4[]
+func (ci ColumnInfo) Label() string {
+  name := strings.ToLower(ci.dbType)
+  suffix := ""
+
+  if strings.HasPrefix(name, "_") {
+    name, suffix = name[1:], "[]"
+  }
+
+  if ci.precision >= 0 && ci.scale >= 0 {
+    name += fmt.Sprintf("(%d,%d)", ci.precision, ci.scale)
+  } else if ci.length >= 0 {
+    name += fmt.Sprintf("(%d)", ci.length)
+  }
+
+  if ci.nullableKnown && !ci.nullable {
+    suffix += " not null"
+  }
+
+  return name + suffix
+}
+
+func (ci ColumnInfo) IsNumeric() bool {
+  switch ci.dbType {
+  case "INT2", "INT4", "INT8", "FLOAT4", "FLOAT8", "NUMERIC", "OID", "MONEY":
+    return true
+  }
+  return false
 }
 
 type DBInfo struct {
+  conn string // name of the saved connection, if any
+
   name, user, pass, host, port string
   ssl bool
+
+  statementTimeout, lockTimeout, idleTimeout string
+
+  protected bool
+  readOnly  bool
 }
 
-func ConnectDb(info *DBInfo) (*sql.DB, error) {
-  connStr := ""
-  connStr += " host=" + info.host
-  connStr += " port=" + info.port
-  connStr += " user=" + info.user
-  connStr += " password=" + info.pass
-  connStr += " dbname=" + info.name
+// Name of the saved connection, or the address of the database
+func (info *DBInfo) Label() string {
+  if info.conn != "" {
+    return info.conn
+  }
+  return info.user + "@" + info.host + ":" + info.port + "/" + info.name
+}
+
+// Quotes a value of the connection string, so spaces and quotes are kept
+func connStrValue(value string) string {
+  value = strings.ReplaceAll(value, `\`, `\\`)
+  value = strings.ReplaceAll(value, `'`, `\'`)
+  return "'" + value + "'"
+}
 
-  if !info.ssl {
+func (info *DBInfo) ConnString() string {
+  connStr := ""
+  connStr += " host=" + connStrValue(info.host)
+  connStr += " port=" + connStrValue(info.port)
+  connStr += " user=" + connStrValue(info.user)
+  connStr += " password=" + connStrValue(info.pass)
+  connStr += " dbname=" + connStrValue(info.name)
+
+  // require is the default of lib/pq, but not of pgconn, used by the exports
+  if info.ssl {
+    connStr += " sslmode=require"
+  } else {
     connStr += " sslmode=disable"
   }
 
-  db, err := sql.Open("postgres", connStr)
+  // Unknown keys are sent by the driver as settings of every new connection
+  if info.statementTimeout != "" {
+    connStr += " statement_timeout=" + connStrValue(info.statementTimeout)
+  }
+  if info.lockTimeout != "" {
+    connStr += " lock_timeout=" + connStrValue(info.lockTimeout)
+  }
+  if info.idleTimeout != "" {
+    connStr += " idle_in_transaction_session_timeout=" + connStrValue(info.idleTimeout)
+  }
+  if info.readOnly {
+    connStr += " default_transaction_read_only=on"
+  }
+
+  return connStr
+}
+
+// Message for the errors caused by the connection timeouts, empty for any other
+func TimeoutMessage(err error) string {
+  pqErr, ok := err.(*pq.Error)
+  if !ok {
+    return ""
+  }
+
+  switch pqErr.Code {
+  case "57014":
+    if strings.Contains(pqErr.Message, "statement timeout") {
+      return "Statement timeout reached, the server cancelled the query."
+    }
+  case "55P03":
+    if strings.Contains(pqErr.Message, "lock timeout") {
+      return "Lock timeout reached while waiting for a lock, the query was cancelled."
+    }
+  case "25P03":
+    return "The session was closed by the idle in transaction timeout."
+  }
+
+  return ""
+}
+
+func ConnectDb(info *DBInfo) (*sql.DB, error) {
+  db, err := sql.Open("postgres", info.ConnString())
   if err != nil {
     return nil, err
   }
@@ -43,35 +181,81 @@ func ConnectDb(info *DBInfo) (*sql.DB, error) {
   return db, err
 }
 
+// Both *sql.DB and *sql.Conn can run queries
+type Queryer interface {
+  QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+  ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func ErrorResult(err error) QueryResult {
+  return QueryResult{columns: []string{}, values: [][]string{}, err: err}
+}
+
 func GetQueryResult(db *sql.DB, query string) QueryResult {
-  result := QueryResult{columns: []string{}, values: [][]string{}, err: nil}
+  return GetQueryResultContext(context.Background(), db, query)
+}
 
-	rows, err := db.Query(query)
+// The query is cancelled (client and server side) once ctx is done
+func GetQueryResultContext(ctx context.Context, db Queryer, query string, args ...interface{}) QueryResult {
+	rows, err := db.QueryContext(ctx, query, args...)
 
   if err != nil {
-    result.err = err
-    return result
+    return ErrorResult(err)
   }
 
   defer rows.Close()
 
-  colNames, err := rows.Columns()
+  return ScanRows(rows)
+}
+
+// Runs a statement that doesn't return rows, keeping the affected row count
+func ExecStatement(ctx context.Context, db Queryer, query string, args ...interface{}) QueryResult {
+  result := QueryResult{columns: []string{}, values: [][]string{}, err: nil, affected: -1}
+
+  res, err := db.ExecContext(ctx, query, args...)
+
   if err != nil {
     result.err = err
     return result
   }
 
-  var myMap = make(map[string]interface{})
+  // Statements like CREATE or SET have no row count
+  affected, err := res.RowsAffected()
+  if err == nil {
+    result.affected = affected
+  }
+
+  return result
+}
+
+func ScanRows(rows *sql.Rows) QueryResult {
+  result := QueryResult{
+    columns: []string{},
+    types: []ColumnInfo{},
+    values: [][]string{},
+    nulls: [][]bool{},
+    err: nil,
+  }
+
+  colTypes, err := rows.ColumnTypes()
+  if err != nil {
+    result.err = err
+    return result
+  }
 
-  cols := make([]interface{}, len(colNames))
-  colPtrs := make([]interface{}, len(colNames))
-  for i := 0; i < len(colNames); i++ {
+  cols := make([]interface{}, len(colTypes))
+  colPtrs := make([]interface{}, len(colTypes))
+  for i := 0; i < len(colTypes); i++ {
     colPtrs[i] = &cols[i]
   }
 
-  var keys = make(map[string]int)
-  result.columns = make([]string, len(colNames))
-  result.values = [][]string{}
+  result.columns = make([]string, len(colTypes))
+  result.types = make([]ColumnInfo, len(colTypes))
+
+  for i, ct := range colTypes {
+    result.types[i] = NewColumnInfo(ct)
+    result.columns[i] = result.types[i].name
+  }
 
   for rows.Next() {
     err = rows.Scan(colPtrs...)
@@ -79,49 +263,70 @@ func GetQueryResult(db *sql.DB, query string) QueryResult {
       result.err = err
       return result
     }
-    for i, col := range cols {
-      myMap[colNames[i]] = col
-      result.columns[i] = fmt.Sprint(colNames[i])
-      keys[colNames[i]] = i
-    }
 
-    row := make([]string, len(colNames))
-
-    for k, val := range myMap {
-      i := keys[k]
-
-      if val == nil {
-        row[i] = "nil"
-        continue
-      }
-
-      t, isTime := val.(time.Time)
-      if isTime {
-        row[i] = fmt.Sprint(t.Format(time.RFC3339))
-      } else if reflect.TypeOf(val).Kind() == reflect.Slice {
-        v, ok := val.([]byte)
-        if ok {
-          row[i] = string(v)
-        } else {
-          row[i] = "-*-*-"
-        }
-      } else {
-        row[i] = fmt.Sprint(val)
-      }
+    row := make([]string, len(colTypes))
+    nulls := make([]bool, len(colTypes))
+
+    for i, val := range cols {
+      nulls[i] = val == nil
+      row[i] = FormatValue(val, result.types[i].dbType)
     }
 
     result.values = append(result.values, row)
+    result.nulls = append(result.nulls, nulls)
   }
 
-  if len(result.values) == 0 {
-    for i := 0; i < len(colNames); i++ {
-      result.columns[i] = fmt.Sprint(colNames[i])
-    }
+  if err = rows.Err(); err != nil {
+    result.err = err
+    return result
   }
 
   return result
 }
 
+// Layouts of the time types, keeping the fractional seconds, so the values
+// shown and exported load back into their columns. timestamptz uses RFC3339Nano.
+var timeLayouts = map[string]string{
+  "DATE":      "2006-01-02",
+  "TIME":      "15:04:05.999999",
+  "TIMETZ":    "15:04:05.999999-07:00",
+  "TIMESTAMP": "2006-01-02 15:04:05.999999",
+}
+
+// Text of a scanned value, NULL gives an empty string
+func FormatValue(val interface{}, dbType string) string {
+  if val == nil {
+    return ""
+  }
+
+  t, isTime := val.(time.Time)
+  if isTime {
+    if layout, ok := timeLayouts[dbType]; ok {
+      return t.Format(layout)
+    }
+    return t.Format(time.RFC3339Nano)
+  }
+
+  if reflect.TypeOf(val).Kind() == reflect.Slice {
+    v, ok := val.([]byte)
+    if !ok {
+      return fmt.Sprint(val)
+    }
+
+    if dbType == "BYTEA" {
+      return "\\x" + hex.EncodeToString(v)
+    }
+    return string(v)
+  }
+
+  return fmt.Sprint(val)
+}
+
+// Tells if the cell of the result is NULL
+func (qr QueryResult) IsNull(row, col int) bool {
+  return row < len(qr.nulls) && col < len(qr.nulls[row]) && qr.nulls[row][col]
+}
+
 func GetOID(db *sql.DB, tablename string) (string, error) {
   query :=
     `SELECT c.oid, n.nspname, c.relname
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+  "github.com/gdamore/tcell"
+  "database/sql"
+  "context"
+  "fmt"
+  "strings"
+)
+
+// Queries whose last result is kept to compare with the next run
+const MAX_REMEMBERED_QUERIES = 20
+
+type DiffState int
+
+const (
+  ROW_SAME    DiffState = 0
+  ROW_ADDED   DiffState = 1
+  ROW_REMOVED DiffState = 2
+  ROW_CHANGED DiffState = 3
+)
+
+type DiffRow struct {
+  state  DiffState
+  values []string
+  nulls  []bool
+
+  changed []bool   // cells that differ from the previous run
+  before  []string // values of the previous run, for the changed cells
+}
+
+// Rows of the current result lined up with the ones of the previous run,
+// removed rows go after the current ones
+type ResultDiff struct {
+  key  []string
+  rows []DiffRow
+
+  added, removed, changed int
+
+  // Rows of one of the runs weren't all fetched, the ones missing from the
+  // fetched pages show up as added or removed
+  partial bool
+}
+
+func (d ResultDiff) Summary() string {
+  summary := fmt.Sprintf("+%d -%d ~%d compared by %s with the previous run",
+    d.added, d.removed, d.changed, strings.Join(d.key, ", "))
+
+  if d.partial {
+    summary += ", partial: only the fetched rows are compared"
+  }
+  return summary
+}
+
+// Results of the same query are remembered by their text, without the spacing
+func DiffQueryKey(query string) string {
+  return strings.Join(strings.Fields(TrimStatement(query)), " ")
+}
+
+// First table read by a query, CTEs and functions aside
+func SourceTable(query string) string {
+  return ParseQuery(query).FirstTable()
+}
+
+func PrimaryKeyColumns(db *sql.DB, table string) []string {
+  if table == "" {
+    return []string{}
+  }
+
+  query :=
+    `SELECT a.attname
+     FROM pg_catalog.pg_index i
+     INNER JOIN pg_catalog.pg_attribute a
+       ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+     WHERE i.indrelid = $1::regclass AND i.indisprimary
+     ORDER BY array_position(i.indkey, a.attnum);`
+
+  result := GetQueryResultContext(context.Background(), db, query, table)
+
+  if result.err != nil {
+    return []string{}
+  }
+
+  return SSMap(result.values, func (row []string) string { return row[0] })
+}
+
+// The primary key when the result has all its columns, then an id column,
+// then the first column
+func DefaultDiffKey(result QueryResult, pk []string) []string {
+  if len(pk) > 0 && len(ColumnIndexes(result.columns, pk)) == len(pk) {
+    return pk
+  }
+
+  for _, name := range result.columns {
+    if strings.ToLower(name) == "id" {
+      return []string{name}
+    }
+  }
+
+  if len(result.columns) > 0 {
+    return []string{result.columns[0]}
+  }
+
+  return []string{}
+}
+
+// Indexes of the given columns, the missing ones are left out
+func ColumnIndexes(columns []string, names []string) []int {
+  indexes := []int{}
+
+  for _, name := range names {
+    for i, column := range columns {
+      if column == name {
+        indexes = append(indexes, i)
+        break
+      }
+    }
+  }
+
+  return indexes
+}
+
+// Key of each row, repeated keys get the number of the occurrence
+func diffRowKeys(result QueryResult, key []int) []string {
+  keys := make([]string, len(result.values))
+  seen := map[string]int{}
+
+  for r, row := range result.values {
+    parts := make([]string, len(key))
+
+    for i, c := range key {
+      if result.IsNull(r, c) {
+        parts[i] = "\x01"
+      } else if c < len(row) {
+        parts[i] = row[c]
+      }
+    }
+
+    k := strings.Join(parts, "\x00")
+    seen[k]++
+    keys[r] = fmt.Sprintf("%s\x02%d", k, seen[k])
+  }
+
+  return keys
+}
+
+func DiffResults(previous, current QueryResult, key []string) ResultDiff {
+  diff := ResultDiff{key: key, rows: []DiffRow{}}
+
+  oldKeys := diffRowKeys(previous, ColumnIndexes(previous.columns, key))
+  newKeys := diffRowKeys(current, ColumnIndexes(current.columns, key))
+
+  // Column of the previous result with the name of each current column
+  mapping := make([]int, len(current.columns))
+  for c, name := range current.columns {
+    mapping[c] = -1
+    if found := ColumnIndexes(previous.columns, []string{name}); len(found) > 0 {
+      mapping[c] = found[0]
+    }
+  }
+
+  oldRows := map[string]int{}
+  for r, k := range oldKeys {
+    oldRows[k] = r
+  }
+
+  matched := map[int]bool{}
+
+  for r, row := range current.values {
+    dr := DiffRow{
+      state: ROW_ADDED,
+      values: row,
+      nulls: make([]bool, len(row)),
+      changed: make([]bool, len(row)),
+      before: make([]string, len(row)),
+    }
+
+    for c := range row {
+      dr.nulls[c] = current.IsNull(r, c)
+    }
+
+    if o, ok := oldRows[newKeys[r]]; ok {
+      matched[o] = true
+      dr.state = ROW_SAME
+
+      for c := range row {
+        m := mapping[c]
+
+        if m < 0 || m >= len(previous.values[o]) {
+          dr.changed[c] = true
+        } else if previous.IsNull(o, m) != dr.nulls[c] ||
+          (!dr.nulls[c] && previous.values[o][m] != row[c]) {
+          dr.changed[c] = true
+          dr.before[c] = previous.values[o][m]
+          if previous.IsNull(o, m) {
+            dr.before[c] = "NULL"
+          }
+        }
+
+        if dr.changed[c] {
+          dr.state = ROW_CHANGED
+        }
+      }
+    }
+
+    switch dr.state {
+    case ROW_ADDED:
+      diff.added++
+    case ROW_CHANGED:
+      diff.changed++
+    }
+
+    diff.rows = append(diff.rows, dr)
+  }
+
+  for o := range previous.values {
+    if matched[o] {
+      continue
+    }
+
+    dr := DiffRow{
+      state: ROW_REMOVED,
+      values: make([]string, len(current.columns)),
+      nulls: make([]bool, len(current.columns)),
+      changed: make([]bool, len(current.columns)),
+      before: make([]string, len(current.columns)),
+    }
+
+    for c, m := range mapping {
+      if m < 0 || m >= len(previous.values[o]) || previous.IsNull(o, m) {
+        dr.nulls[c] = true
+      } else {
+        dr.values[c] = previous.values[o][m]
+      }
+    }
+
+    diff.removed++
+    diff.rows = append(diff.rows, dr)
+  }
+
+  return diff
+}
+
+// Shows a diff like a result, with a mark instead of the row number:
+// added rows in green, removed in red and changed cells in yellow
+func TableSetDiff(table *tview.Table, result QueryResult, diff ResultDiff) {
+  if len(diff.rows) == 0 {
+    TableSetResult(table, result)
+    return
+  }
+
+  TableSetHeader(table, result)
+
+  for r, dr := range diff.rows {
+    at := RESULT_HEADER_ROWS + r
+
+    mark, color := fmt.Sprintf("%d", r), tcell.ColorYellow
+    switch dr.state {
+    case ROW_ADDED:
+      mark, color = "+", tcell.ColorGreen
+    case ROW_REMOVED:
+      mark, color = "-", tcell.ColorRed
+    case ROW_CHANGED:
+      mark = "~"
+    }
+
+    table.SetCell(at, 0,
+      tview.NewTableCell(mark).
+        SetTextColor(color).
+        SetAlign(tview.AlignCenter))
+
+    for c, value := range dr.values {
+      column := ColumnInfo{}
+      if c < len(result.types) {
+        column = result.types[c]
+      }
+
+      cell := ResultCell(value, dr.nulls[c], column)
+
+      switch {
+      case dr.state == ROW_ADDED:
+        cell.SetTextColor(tcell.ColorGreen)
+      case dr.state == ROW_REMOVED:
+        cell.SetTextColor(tcell.ColorRed)
+      case dr.changed[c]:
+        cell.SetTextColor(tcell.ColorBlack).SetBackgroundColor(tcell.ColorYellow)
+      }
+
+      table.SetCell(at, c + 1, cell)
+    }
+  }
+}
@@ -4,8 +4,12 @@ import (
 	"github.com/rivo/tview"
   "github.com/gdamore/tcell"
   "fmt"
+  "time"
 )
 
+// How long the statement run by Ctrl-X stays marked
+const FLASH_TIME = 300 * time.Millisecond
+
 type HColor int
 
 const (
@@ -58,9 +62,16 @@ type Editor struct {
 
   onModeChanged func(Mode)
   onExecute func(string)
+  onExplain func(string, bool)
+  onExecuteJob func(string)
 
   selected VisualSelect
 
+  // Statement sent by Ctrl-X in normal mode, shown for a moment
+  flashStart, flashEnd int
+  flashCount int
+  queueUpdate func(func())
+
   buffCommand string // buffer that save a multi-letter command
   yankedLines Text  // used to save copied/deleted text
 }
@@ -77,6 +88,12 @@ func NewEditor() *Editor {
     },
     onExecute: func(s string) {
     },
+    onExplain: func(s string, analyze bool) {
+    },
+    onExecuteJob: func(s string) {
+    },
+    queueUpdate: func(f func()) {
+    },
   }
 
   e.history = NewDumbHistory(5)
@@ -220,6 +237,8 @@ func (e *Editor) HandleKeyboard(ch rune, key tcell.Key) bool {
         e.MoveCursorRight()
       case tcell.KeyLeft:
         e.MoveCursorLeft()
+      case tcell.KeyCtrlX:
+        e.ExecuteAtCursor()
       case tcell.KeyCtrlR:
         state := e.history.Redo()
         if state != nil {
@@ -332,6 +351,12 @@ func (e *Editor) HandleKeyboard(ch rune, key tcell.Key) bool {
 
     if key == tcell.KeyCtrlX {
       e.onExecute(e.GetSelectedText())
+    } else if key == tcell.KeyCtrlP {
+      e.onExplain(e.GetSelectedText(), false)
+    } else if key == tcell.KeyCtrlA {
+      e.onExplain(e.GetSelectedText(), true)
+    } else if key == tcell.KeyCtrlB {
+      e.onExecuteJob(e.GetSelectedText())
     }
   } else {
     switch key {
@@ -591,6 +616,102 @@ func (e *Editor) SetExecuteCb(cb func(string)) {
   e.onExecute = cb
 }
 
+func (e *Editor) SetExplainCb(cb func(string, bool)) {
+  e.onExplain = cb
+}
+
+func (e *Editor) SetExecuteJobCb(cb func(string)) {
+  e.onExecuteJob = cb
+}
+
+// Changes to the editor from other goroutines, like the end of a flash
+func (e *Editor) SetQueueUpdateCb(cb func(func())) {
+  e.queueUpdate = cb
+}
+
+// Position of the cursor in the text
+func (e *Editor) CursorOffset() int {
+  offset := e.cursorX
+  for i := 0; i < e.cursorY; i++ {
+    offset += e.text.LineLen(i) + 1
+  }
+  return offset
+}
+
+// Statement under the cursor, with the position of the cursor in it
+func (e *Editor) StatementAtCursor() (string, int, bool) {
+  script := e.text.String()
+  offset := e.CursorOffset()
+
+  start, end, ok := StatementAt(script, offset)
+  if !ok {
+    return "", 0, false
+  }
+
+  return string([]rune(script)[start:end]), offset - start, true
+}
+
+// Sends the statement under the cursor to onExecute, flashing it
+func (e *Editor) ExecuteAtCursor() {
+  script := e.text.String()
+
+  start, end, ok := StatementAt(script, e.CursorOffset())
+  if !ok {
+    return
+  }
+
+  e.Flash(e.FullTextPos(start), e.FullTextPos(end))
+  e.onExecute(string([]rune(script)[start:end]))
+}
+
+// Position in the full text (with line numbers) of a position in the text
+func (e *Editor) FullTextPos(offset int) int {
+  pos := 0
+
+  for i := 0; i < e.text.Len(); i++ {
+    if offset <= e.text.LineLen(i) {
+      return pos + e.numbersShift + offset
+    }
+
+    offset -= e.text.LineLen(i) + 1
+    pos += e.text.LineLen(i) + e.numbersShift + 2
+  }
+
+  return pos
+}
+
+func (e *Editor) Flash(start, end int) {
+  e.flashStart, e.flashEnd = start, end
+  e.flashCount++
+  count := e.flashCount
+
+  e.UpdateText()
+
+  time.AfterFunc(FLASH_TIME, func () {
+    e.queueUpdate(func () {
+      if e.flashCount == count {
+        e.flashStart, e.flashEnd = 0, 0
+        e.UpdateText()
+      }
+    })
+  })
+}
+
+// Splits the highlight that contains the position in two
+func SplitHighlights(highlights []Highlight, at int) []Highlight {
+  result := []Highlight{}
+
+  for _, hl := range highlights {
+    if hl.start < at && at < hl.end {
+      result = append(result, Highlight{hl.start, at, hl.color}, Highlight{at, hl.end, hl.color})
+    } else {
+      result = append(result, hl)
+    }
+  }
+
+  return result
+}
+
 func (e *Editor) SetText(text Text) {
   e.SaveHistory()
 
@@ -640,7 +761,8 @@ func (e *Editor) GetFullText() []rune {
 }
 
 func Colorize(tt TokenType) bool {
-  return tt == NUMBER || tt == STRING || tt == TYPE || tt == KEYWORD || tt == COMMENT
+  return tt == NUMBER || tt == STRING || tt == TYPE || tt == KEYWORD || tt == UNRESERVED ||
+    tt == COMMENT || tt == OPERATOR || tt == CAST || tt == PARAM
 }
 
 func (e *Editor) GenHighlight() {
@@ -670,6 +792,9 @@ func (e *Editor) GenHighlight() {
       if token.ttype == KEYWORD {
         hl := Highlight{ token.start, token.start + token.size, Violet }
         e.highlights = append(e.highlights, hl)
+      } else if token.ttype == UNRESERVED {
+        hl := Highlight{ token.start, token.start + token.size, Pink }
+        e.highlights = append(e.highlights, hl)
       } else if token.ttype == STRING {
         hl := Highlight{ token.start, token.start + token.size, Yellow }
         e.highlights = append(e.highlights, hl)
@@ -679,6 +804,12 @@ func (e *Editor) GenHighlight() {
       } else if token.ttype == COMMENT {
         hl := Highlight{ token.start, token.start + token.size, Wheat }
         e.highlights = append(e.highlights, hl)
+      } else if token.ttype == OPERATOR || token.ttype == CAST {
+        hl := Highlight{ token.start, token.start + token.size, Blue }
+        e.highlights = append(e.highlights, hl)
+      } else if token.ttype == PARAM {
+        hl := Highlight{ token.start, token.start + token.size, Green }
+        e.highlights = append(e.highlights, hl)
       } else if token.ttype == TYPE {
         hl := Highlight{ token.start, token.start + token.size, Turquoise }
         e.highlights = append(e.highlights, hl)
@@ -710,7 +841,14 @@ func (e *Editor) GetParsedText() []rune {
 
   parsedText := []rune{}
 
-  for _, hl := range e.highlights {
+  highlights := e.highlights
+  flashing := e.flashStart < e.flashEnd
+
+  if flashing {
+    highlights = SplitHighlights(SplitHighlights(highlights, e.flashStart), e.flashEnd)
+  }
+
+  for _, hl := range highlights {
     value := append([]rune{}, text[hl.start: hl.end]...)
 
     if (e.mode != VISUAL) && pos >= hl.start && pos < hl.end {
@@ -740,6 +878,10 @@ func (e *Editor) GetParsedText() []rune {
       value = Tint(value, "turquoise")
     }
 
+    if flashing && hl.start >= e.flashStart && hl.end <= e.flashEnd {
+      value = append(append([]rune("[:darkslateblue]"), value...), []rune("[:-]")...)
+    }
+
     parsedText = append(parsedText, value...)
   }
 
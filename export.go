@@ -0,0 +1,277 @@
+package main
+
+import (
+  "bufio"
+  "encoding/json"
+  "errors"
+  "math"
+  "os"
+  "strconv"
+  "strings"
+
+	"github.com/lib/pq"
+)
+
+var exportFormats = []string{"csv", "tsv", "json", "ndjson", "md", "sql:<table>"}
+
+// Writes a query result to a file. The format sql:<table> writes INSERT
+// statements for the given table.
+func ExportResult(result QueryResult, format, path string) error {
+  table := ""
+  if strings.HasPrefix(format, "sql:") {
+    format, table = "sql", format[4:]
+    if table == "" {
+      return errors.New("Missing table name, use sql:<table>.")
+    }
+  }
+
+  var write func(*bufio.Writer, QueryResult) error
+
+  switch format {
+  case "csv":
+    write = writeCsv
+  case "tsv":
+    write = writeTsv
+  case "json":
+    write = writeJson
+  case "ndjson":
+    write = writeNdjson
+  case "md", "markdown":
+    write = writeMarkdown
+  case "sql":
+    write = func (w *bufio.Writer, result QueryResult) error {
+      return writeInserts(w, result, table)
+    }
+  default:
+    return errors.New("Unknown format, use one of " + strings.Join(exportFormats, ", ") + ".")
+  }
+
+  path, err := ExpandHomeDir(path)
+  if err != nil {
+    return err
+  }
+
+  file, err := os.Create(path)
+  if err != nil {
+    return err
+  }
+
+  w := bufio.NewWriter(file)
+
+  if err := write(w, result); err != nil {
+    file.Close()
+    return err
+  }
+
+  if err := w.Flush(); err != nil {
+    file.Close()
+    return err
+  }
+
+  return file.Close()
+}
+
+func resultColumn(result QueryResult, col int) ColumnInfo {
+  if col < len(result.types) {
+    return result.types[col]
+  }
+  return ColumnInfo{}
+}
+
+// NULLs are empty fields and empty strings are quoted, like COPY does
+func writeCsv(w *bufio.Writer, result QueryResult) error {
+  writeCsvRecord(w, result.columns, nil)
+
+  for r, row := range result.values {
+    nulls := make([]bool, len(row))
+    for c := range row {
+      nulls[c] = result.IsNull(r, c)
+    }
+    writeCsvRecord(w, row, nulls)
+  }
+
+  return nil
+}
+
+func writeCsvRecord(w *bufio.Writer, record []string, nulls []bool) {
+  for c, value := range record {
+    if c > 0 {
+      w.WriteByte(',')
+    }
+
+    if nulls != nil && nulls[c] {
+      continue
+    }
+
+    if value == "" || strings.ContainsAny(value, ",\"\r\n") ||
+      strings.TrimSpace(value) != value {
+      w.WriteString(`"` + strings.ReplaceAll(value, `"`, `""`) + `"`)
+    } else {
+      w.WriteString(value)
+    }
+  }
+
+  w.WriteString("\r\n")
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// Same as the text format of COPY, NULLs are \N
+func writeTsv(w *bufio.Writer, result QueryResult) error {
+  header := []string{}
+  for _, name := range result.columns {
+    header = append(header, tsvEscaper.Replace(name))
+  }
+  w.WriteString(strings.Join(header, "\t") + "\n")
+
+  for r, row := range result.values {
+    for c, value := range row {
+      if c > 0 {
+        w.WriteByte('\t')
+      }
+
+      if result.IsNull(r, c) {
+        w.WriteString(`\N`)
+      } else {
+        w.WriteString(tsvEscaper.Replace(value))
+      }
+    }
+    w.WriteByte('\n')
+  }
+
+  return nil
+}
+
+// The value as a JSON literal, numbers, booleans and json columns are kept
+// as they are
+func jsonValue(value string, null bool, column ColumnInfo) json.RawMessage {
+  if null {
+    return json.RawMessage("null")
+  }
+
+  switch column.dbType {
+  case "INT2", "INT4", "INT8", "FLOAT4", "FLOAT8", "NUMERIC", "OID":
+    n, err := strconv.ParseFloat(value, 64)
+    if err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+      return json.RawMessage(value)
+    }
+  case "BOOL":
+    if value == "true" || value == "false" {
+      return json.RawMessage(value)
+    }
+  case "JSON", "JSONB":
+    if json.Valid([]byte(value)) {
+      return json.RawMessage(value)
+    }
+  }
+
+  quoted, _ := json.Marshal(value)
+  return json.RawMessage(quoted)
+}
+
+// One JSON object per row, keeping the order of the columns
+func jsonRow(result QueryResult, r int) string {
+  fields := []string{}
+
+  for c, value := range result.values[r] {
+    name, _ := json.Marshal(result.columns[c])
+    fields = append(fields,
+      string(name) + ":" + string(jsonValue(value, result.IsNull(r, c), resultColumn(result, c))))
+  }
+
+  return "{" + strings.Join(fields, ",") + "}"
+}
+
+func writeJson(w *bufio.Writer, result QueryResult) error {
+  w.WriteString("[")
+
+  for r := range result.values {
+    if r > 0 {
+      w.WriteString(",")
+    }
+    w.WriteString("\n  " + jsonRow(result, r))
+  }
+
+  w.WriteString("\n]\n")
+  return nil
+}
+
+func writeNdjson(w *bufio.Writer, result QueryResult) error {
+  for r := range result.values {
+    w.WriteString(jsonRow(result, r) + "\n")
+  }
+  return nil
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func writeMarkdown(w *bufio.Writer, result QueryResult) error {
+  header, line := []string{}, []string{}
+
+  for c, name := range result.columns {
+    header = append(header, markdownEscaper.Replace(name))
+
+    if resultColumn(result, c).IsNumeric() {
+      line = append(line, "---:")
+    } else {
+      line = append(line, "---")
+    }
+  }
+
+  w.WriteString("| " + strings.Join(header, " | ") + " |\n")
+  w.WriteString("| " + strings.Join(line, " | ") + " |\n")
+
+  for r, row := range result.values {
+    cells := []string{}
+    for c, value := range row {
+      if result.IsNull(r, c) {
+        cells = append(cells, "NULL")
+      } else {
+        cells = append(cells, markdownEscaper.Replace(value))
+      }
+    }
+    w.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+  }
+
+  return nil
+}
+
+// The value as a SQL literal
+func sqlValue(value string, null bool, column ColumnInfo) string {
+  if null {
+    return "NULL"
+  }
+
+  switch column.dbType {
+  case "INT2", "INT4", "INT8", "FLOAT4", "FLOAT8", "NUMERIC", "OID":
+    n, err := strconv.ParseFloat(value, 64)
+    if err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+      return value
+    }
+  case "BOOL":
+    if value == "true" || value == "false" {
+      return value
+    }
+  }
+
+  return pq.QuoteLiteral(value)
+}
+
+func writeInserts(w *bufio.Writer, result QueryResult, table string) error {
+  columns := []string{}
+  for _, name := range result.columns {
+    columns = append(columns, pq.QuoteIdentifier(name))
+  }
+
+  prefix := "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES ("
+
+  for r, row := range result.values {
+    values := []string{}
+    for c, value := range row {
+      values = append(values, sqlValue(value, result.IsNull(r, c), resultColumn(result, c)))
+    }
+    w.WriteString(prefix + strings.Join(values, ", ") + ");\n")
+  }
+
+  return nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+  "strings"
+)
+
+// Statement that changes or removes more than a slip of the keyboard should
+type Hazard struct {
+  action  string   // like "DELETE without WHERE" or "DROP TABLE"
+  objects []string // names of the objects affected
+}
+
+func (h Hazard) String() string {
+  return h.action + ": " + strings.Join(h.objects, ", ")
+}
+
+// Reads the words of a statement, skipping the ones inside parentheses when asked
+type wordReader struct {
+  tokens []Token
+  input  []rune
+  pos    int
+}
+
+func (wr *wordReader) peek() string {
+  if wr.pos < len(wr.tokens) {
+    return LowerWord(wr.input, wr.tokens[wr.pos])
+  }
+  return ""
+}
+
+// Tells if the current token is the given punctuation
+func (wr *wordReader) punct(c rune) bool {
+  if wr.pos < len(wr.tokens) && wr.tokens[wr.pos].Is(PUNCT) {
+    return wr.input[wr.tokens[wr.pos].start] == c
+  }
+  return false
+}
+
+func (wr *wordReader) skip(words ...string) bool {
+  for _, word := range words {
+    if wr.peek() == word {
+      wr.pos++
+      return true
+    }
+  }
+  return false
+}
+
+// A name that can be qualified, like schema.table
+func (wr *wordReader) name() string {
+  if wr.pos >= len(wr.tokens) {
+    return ""
+  }
+
+  name := wr.tokens[wr.pos].Text(wr.input)
+  wr.pos++
+
+  for wr.punct('.') && wr.pos + 1 < len(wr.tokens) {
+    name += "." + wr.tokens[wr.pos + 1].Text(wr.input)
+    wr.pos += 2
+  }
+
+  return name
+}
+
+// Names separated by commas, until one of the stop words
+func (wr *wordReader) names(stop ...string) []string {
+  names := []string{}
+
+  for wr.pos < len(wr.tokens) {
+    for _, word := range stop {
+      if wr.peek() == word {
+        return names
+      }
+    }
+
+    names = append(names, wr.name())
+
+    if !wr.skip(",") {
+      break
+    }
+  }
+
+  return names
+}
+
+// Moves to the next word at the top level, tells if it was found
+func (wr *wordReader) find(word string) bool {
+  depth := 0
+
+  for ; wr.pos < len(wr.tokens); wr.pos++ {
+    switch {
+    case wr.punct('('):
+      depth++
+    case wr.punct(')'):
+      depth--
+    case depth == 0 && wr.peek() == word:
+      return true
+    }
+  }
+
+  return false
+}
+
+// UPDATE or DELETE without WHERE, in the query or in one of its CTEs and
+// subqueries, like WITH d AS (DELETE FROM t RETURNING *) SELECT ...
+func writeHazard(q *Query) (Hazard, bool) {
+  if (q.verb == "update" || q.verb == "delete") && !q.where {
+    name := ""
+    if len(q.tables) > 0 {
+      name = q.tables[0].name
+    }
+
+    return Hazard{strings.ToUpper(q.verb) + " without WHERE", []string{name}}, true
+  }
+
+  for _, child := range q.children {
+    if hazard, ok := writeHazard(child); ok {
+      return hazard, true
+    }
+  }
+
+  return Hazard{}, false
+}
+
+// Tells if the statement has an UPDATE or DELETE without WHERE, or is a DROP,
+// a TRUNCATE or an ALTER ... DROP, also when EXPLAIN ANALYZE runs it
+func FindHazard(statement string) (Hazard, bool) {
+  tokens, input := Tokenize(statement)
+
+  i := mainVerb(tokens, input)
+  if i < 0 {
+    return Hazard{}, false
+  }
+
+  // EXPLAIN ANALYZE runs the statement, the plain one only plans it
+  if LowerWord(input, tokens[i]) == "explain" {
+    j, analyze := explainTarget(tokens, input, i)
+    if !analyze || j < 0 {
+      return Hazard{}, false
+    }
+    return FindHazard(string(input[tokens[j].start:]))
+  }
+
+  if hazard, ok := writeHazard(ParseQuery(statement)); ok {
+    return hazard, true
+  }
+
+  wr := &wordReader{tokens: tokens, input: input, pos: i + 1}
+
+  switch verb := LowerWord(input, tokens[i]); verb {
+  case "truncate":
+    wr.skip("table")
+    wr.skip("only")
+    names := wr.names("restart", "continue", "cascade", "restrict")
+
+    return Hazard{"TRUNCATE", names}, true
+
+  case "drop":
+    kind := wr.peek()
+    wr.pos++
+
+    if kind == "materialized" || kind == "foreign" || kind == "access" || kind == "event" ||
+      kind == "text" || kind == "user" && wr.peek() == "mapping" {
+      kind += " " + wr.peek()
+      wr.pos++
+    }
+
+    wr.skip("concurrently")
+    if wr.skip("if") {
+      wr.skip("exists")
+    }
+
+    names := wr.names("cascade", "restrict", "on", "(")
+
+    return Hazard{"DROP " + strings.ToUpper(kind), names}, true
+
+  case "alter":
+    kind := strings.ToUpper(wr.peek())
+    wr.pos++
+
+    if wr.skip("if") {
+      wr.skip("exists")
+    }
+    wr.skip("only")
+    parent := wr.name()
+
+    dropped := []string{}
+
+    for wr.find("drop") {
+      wr.pos++
+
+      // ALTER COLUMN ... DROP DEFAULT and friends only drop a property
+      switch wr.peek() {
+      case "default", "not", "identity", "expression":
+        continue
+      }
+
+      what := "column"
+      switch wr.peek() {
+      case "column", "constraint", "attribute", "value":
+        what = wr.peek()
+        wr.pos++
+      }
+
+      if wr.skip("if") {
+        wr.skip("exists")
+      }
+
+      dropped = append(dropped, what + " " + parent + "." + wr.name())
+    }
+
+    if len(dropped) == 0 {
+      return Hazard{}, false
+    }
+
+    return Hazard{"ALTER " + kind + " ... DROP", dropped}, true
+  }
+
+  return Hazard{}, false
+}
+
+func ScriptHazards(statements []string) []Hazard {
+  hazards := []Hazard{}
+
+  for _, statement := range statements {
+    if hazard, ok := FindHazard(statement); ok {
+      hazards = append(hazards, hazard)
+    }
+  }
+
+  return hazards
+}
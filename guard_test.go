@@ -0,0 +1,45 @@
+package main
+
+import (
+  "testing"
+)
+
+func TestFindHazard(t *testing.T) {
+  tests := []struct {
+    statement string
+    hazard    string // empty when there is none
+  }{
+    {"select * from orders", ""},
+    {"delete from orders where id = 1", ""},
+    {"delete from orders", "DELETE without WHERE: orders"},
+    {"update t set a = 1", "UPDATE without WHERE: t"},
+    {"update t set a = (select 1 where true)", "UPDATE without WHERE: t"},
+    {"with d as (delete from t returning *) select * from d", "DELETE without WHERE: t"},
+    {"with d as (delete from t where a returning *) select * from d", ""},
+    {"select * from (select 1) s where exists (select 1)", ""},
+    {"explain delete from orders", ""},
+    {"EXPLAIN ANALYZE DELETE FROM orders", "DELETE without WHERE: orders"},
+    {"EXPLAIN (ANALYZE) UPDATE t SET a=1", "UPDATE without WHERE: t"},
+    {"explain (analyze, buffers) update t set a = 1 where id = 2", ""},
+    {"explain analyze verbose drop table t", "DROP TABLE: t"},
+    {"truncate table a, b cascade", "TRUNCATE: a, b"},
+    {"drop table if exists public.t", "DROP TABLE: public.t"},
+    {"drop materialized view v", "DROP MATERIALIZED VIEW: v"},
+    {"alter table t drop column c", "ALTER TABLE ... DROP: column t.c"},
+    {"alter table t alter column c drop default", ""},
+    {"insert into t values (1)", ""},
+  }
+
+  for _, test := range tests {
+    hazard, ok := FindHazard(test.statement)
+
+    got := ""
+    if ok {
+      got = hazard.String()
+    }
+
+    if got != test.hazard {
+      t.Errorf("%q: got %q, want %q", test.statement, got, test.hazard)
+    }
+  }
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+  "github.com/gdamore/tcell"
+  "fmt"
+  "strings"
+)
+
+var historyFields = []string{"Time", "Connection", "Duration", "Rows", "Status", "Query"}
+
+var historyFilters = []string{"all", "succeeded", "failed"}
+
+type HistoryPage struct {
+  search  *tview.InputField
+  filters *tview.TextView
+  table   *tview.Table
+
+  layout *tview.Grid
+
+  focusedType ComponentType
+
+  connection string // empty for every connection
+  success    int
+
+  entries []HistoryEntry
+}
+
+func NewHistoryPage(c *Context) *HistoryPage {
+  hp := &HistoryPage{entries: []HistoryEntry{}}
+
+  hp.focusedType = MENU
+
+  hp.search = tview.NewInputField().
+    SetLabel(" Search: ").
+    SetFieldBackgroundColor(tcell.NewRGBColor(100, 50, 200))
+
+  hp.search.SetChangedFunc(func (text string) {
+    hp.Refresh(c)
+  })
+
+  hp.search.SetDoneFunc(func (key tcell.Key) {
+    hp.SetCompType(HISTORY)
+    c.SetFocus(hp.table)
+  })
+
+  hp.filters = tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(false)
+
+  hp.table = tview.NewTable().
+		SetBorders(false).
+		SetSeparator(tview.Borders.Vertical)
+
+  hp.table.SetSelectable(true, false)
+
+	hp.layout = tview.NewGrid().
+		SetBorders(true).
+		SetRows(1, 1, 1, -1).
+		SetColumns(-1).
+		AddItem(c.menuBar,   0, 0, 1, 1, 0, 0, false).
+		AddItem(hp.search,   1, 0, 1, 1, 0, 0, false).
+		AddItem(hp.filters,  2, 0, 1, 1, 0, 0, false).
+		AddItem(hp.table,    3, 0, 1, 1, 0, 0, true)
+
+  hp.layout.
+    SetInputCapture(func (event *tcell.EventKey) *tcell.EventKey {
+      if hp.focusedType == MENU {
+        c.HandleMenuKeyInput(event)
+        return event
+      }
+
+      if hp.focusedType == SEARCH {
+        return event
+      }
+
+      switch event.Rune() {
+      case 'q':
+        hp.SetCompType(MENU)
+        c.FocusMenu()
+
+      case '/':
+        hp.SetCompType(SEARCH)
+        c.SetFocus(hp.search)
+
+      case 'c':
+        hp.NextConnection(c)
+
+      case 's':
+        hp.success = (hp.success + 1) % len(historyFilters)
+        hp.Refresh(c)
+
+      case 'e', 'r':
+        row, _ := hp.table.GetSelection()
+        if row < 1 || row > len(hp.entries) {
+          return nil
+        }
+
+        hp.SetCompType(MENU)
+        c.runPage.Recall(c, hp.entries[row - 1].Query, event.Rune() == 'r')
+
+      default:
+        return event
+      }
+
+      return nil
+    })
+
+  return hp
+}
+
+func (hp *HistoryPage) SetCompType(t ComponentType) {
+  hp.focusedType = t
+}
+
+// Moves the connection filter to the next connection found in the history
+func (hp *HistoryPage) NextConnection(c *Context) {
+  connections := append([]string{""}, c.history.Connections()...)
+
+  next := 0
+  for i, connection := range connections {
+    if connection == hp.connection {
+      next = (i + 1) % len(connections)
+      break
+    }
+  }
+
+  hp.connection = connections[next]
+  hp.Refresh(c)
+}
+
+func (hp *HistoryPage) Refresh(c *Context) {
+  hp.entries = c.history.Search(hp.search.GetText(), hp.connection, hp.success)
+
+  connection := hp.connection
+  if connection == "" {
+    connection = "all"
+  }
+
+  hp.filters.SetText(fmt.Sprintf(
+    " [yellow]c[white]onnection: %s  [yellow]s[white]tatus: %s  |  %d queries  |  " +
+    "[yellow]/[white] search, [yellow]e[white] to the editor, [yellow]r[white] run again",
+    tview.Escape(connection), historyFilters[hp.success], len(hp.entries)))
+
+  values := [][]string{}
+
+  for _, entry := range hp.entries {
+    status := "ok"
+    if entry.Error != "" {
+      status = entry.Error
+    }
+
+    query := strings.Join(strings.Fields(entry.Query), " ")
+
+    rows := fmt.Sprintf("%d", entry.Rows)
+    if entry.Partial {
+      rows += "+"
+    }
+
+    values = append(values, []string{
+      entry.Time.Local().Format("2006-01-02 15:04:05"),
+      tview.Escape(entry.Connection),
+      fmt.Sprintf("%.1fms", entry.Duration),
+      rows,
+      tview.Escape(StrClip(status, 40)),
+      tview.Escape(StrClip(query, 200)),
+    })
+  }
+
+  TableSetData(hp.table, historyFields, values, false)
+
+  for i, entry := range hp.entries {
+    if entry.Error != "" {
+      hp.table.GetCell(i + 1, 4).SetTextColor(tcell.ColorRed)
+    }
+  }
+
+  hp.table.Select(1, 0)
+  hp.table.ScrollToBeginning()
+}
+
+func (hp *HistoryPage) Layout() tview.Primitive {
+  return hp.layout
+}
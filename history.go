@@ -0,0 +1,220 @@
+package main
+
+import (
+  "bufio"
+  "encoding/json"
+  "os"
+  "sort"
+  "strings"
+  "sync"
+  "time"
+  "unicode"
+)
+
+// Entries loaded from the history file, the older ones stay only in the file
+const MAX_HISTORY = 5000
+
+type HistoryEntry struct {
+  Query      string    `json:"query"`
+  Connection string    `json:"connection"`
+  Time       time.Time `json:"time"`
+  Duration   float64   `json:"duration_ms"`
+  Rows       int64     `json:"rows"`
+  Error      string    `json:"error,omitempty"`
+
+  // The result had more rows than the ones fetched when it was saved, which
+  // are the only ones Rows counts
+  Partial bool `json:"partial,omitempty"`
+}
+
+func NewHistoryEntry(info *DBInfo, sr *StatementResult) HistoryEntry {
+  entry := HistoryEntry{
+    Query: TrimStatement(sr.query),
+    Connection: info.Label(),
+    Time: time.Now().Add(-sr.duration),
+    Duration: float64(sr.duration.Microseconds()) / 1000,
+    Rows: sr.Rows(),
+    Partial: sr.Partial(),
+  }
+
+  if sr.cancelled {
+    entry.Error = "cancelled"
+  } else if sr.result.err != nil {
+    entry.Error = sr.result.err.Error()
+  }
+
+  return entry
+}
+
+// Every statement run, kept in ~/.postdigress_history, one JSON object per line
+type QueryHistory struct {
+  path string
+
+  mutex   sync.Mutex
+  entries []HistoryEntry
+}
+
+func OpenQueryHistory() *QueryHistory {
+  h := &QueryHistory{entries: []HistoryEntry{}}
+
+  path, err := ExpandHomeDir("~/.postdigress_history")
+  if err != nil {
+    return h
+  }
+  h.path = path
+
+  file, err := os.Open(path)
+  if err != nil {
+    return h
+  }
+  defer file.Close()
+
+  scanner := bufio.NewScanner(file)
+  scanner.Buffer(make([]byte, 64 * 1024), 16 * 1024 * 1024)
+
+  for scanner.Scan() {
+    entry := HistoryEntry{}
+    if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+      h.entries = append(h.entries, entry)
+    }
+  }
+
+  if len(h.entries) > MAX_HISTORY {
+    h.entries = h.entries[len(h.entries) - MAX_HISTORY:]
+  }
+
+  return h
+}
+
+func (h *QueryHistory) Add(entry HistoryEntry) error {
+  h.mutex.Lock()
+  defer h.mutex.Unlock()
+
+  h.entries = append(h.entries, entry)
+  if len(h.entries) > MAX_HISTORY {
+    h.entries = h.entries[1:]
+  }
+
+  if h.path == "" {
+    return nil
+  }
+
+  data, err := json.Marshal(entry)
+  if err != nil {
+    return err
+  }
+
+  file, err := os.OpenFile(h.path, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0600)
+  if err != nil {
+    return err
+  }
+
+  _, err = file.Write(append(data, '\n'))
+  if err != nil {
+    file.Close()
+    return err
+  }
+
+  return file.Close()
+}
+
+func (h *QueryHistory) Connections() []string {
+  h.mutex.Lock()
+  defer h.mutex.Unlock()
+
+  seen := map[string]bool{}
+  connections := []string{}
+
+  for _, entry := range h.entries {
+    if !seen[entry.Connection] {
+      seen[entry.Connection] = true
+      connections = append(connections, entry.Connection)
+    }
+  }
+
+  sort.Strings(connections)
+  return connections
+}
+
+// Success filter of a search
+const (
+  HISTORY_ALL = iota
+  HISTORY_SUCCEEDED
+  HISTORY_FAILED
+)
+
+// Entries matching the pattern, the best matches first and then the newest
+func (h *QueryHistory) Search(pattern, connection string, success int) []HistoryEntry {
+  h.mutex.Lock()
+  defer h.mutex.Unlock()
+
+  type match struct {
+    entry HistoryEntry
+    score int
+    index int
+  }
+
+  matches := []match{}
+
+  for i, entry := range h.entries {
+    if connection != "" && entry.Connection != connection {
+      continue
+    }
+
+    if success == HISTORY_SUCCEEDED && entry.Error != "" ||
+      success == HISTORY_FAILED && entry.Error == "" {
+      continue
+    }
+
+    score, ok := FuzzyScore(pattern, entry.Query)
+    if ok {
+      matches = append(matches, match{entry, score, i})
+    }
+  }
+
+  sort.SliceStable(matches, func (i, j int) bool {
+    if matches[i].score != matches[j].score {
+      return matches[i].score > matches[j].score
+    }
+    return matches[i].index > matches[j].index
+  })
+
+  entries := []HistoryEntry{}
+  for _, m := range matches {
+    entries = append(entries, m.entry)
+  }
+
+  return entries
+}
+
+// Tells if the letters of the pattern show up in the text in the same order.
+// Letters next to each other, or at the start of words, score higher.
+func FuzzyScore(pattern, text string) (int, bool) {
+  p := []rune(strings.ToLower(strings.Join(strings.Fields(pattern), "")))
+  t := []rune(strings.ToLower(text))
+
+  if len(p) == 0 {
+    return 0, true
+  }
+
+  score, j, last := 0, 0, -2
+
+  for i := 0; i < len(t) && j < len(p); i++ {
+    if t[i] != p[j] {
+      continue
+    }
+
+    score++
+    if last == i - 1 {
+      score += 2
+    }
+    if i == 0 || !unicode.IsLetter(t[i - 1]) && !unicode.IsDigit(t[i - 1]) {
+      score += 1
+    }
+
+    last = i
+    j++
+  }
+
+  return score, j == len(p)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+  "github.com/gdamore/tcell"
+  "context"
+  "fmt"
+  "unicode/utf8"
+)
+
+// Rows of the file shown in the dialog
+const CSV_PREVIEW_ROWS = 6
+
+// Dialog over the SQL pages to choose how a CSV file is copied into a table
+type ImportDialog struct {
+  imp *CsvImport
+
+  title   *tview.TextView
+  preview *tview.Table
+  form    *tview.Form
+  msg     *tview.TextView
+
+  layout *tview.Grid
+
+  records [][]string
+  cancel  context.CancelFunc
+}
+
+func NewImportDialog(c *Context, imp *CsvImport) *ImportDialog {
+  d := &ImportDialog{imp: imp}
+
+  d.title = tview.NewTextView().
+		SetDynamicColors(true).
+    SetText(fmt.Sprintf(" Import [yellow]%s[white] into [yellow]%s[white]",
+      tview.Escape(imp.path), tview.Escape(imp.name)))
+
+  d.preview = tview.NewTable().
+		SetBorders(false).
+		SetSeparator(tview.Borders.Vertical)
+
+  d.msg = tview.NewTextView().
+		SetDynamicColors(true)
+
+  d.form = tview.NewForm()
+
+  d.form.
+    AddCheckbox("Header", imp.header, func (checked bool) {
+      imp.header = checked
+      d.Reload()
+    }).
+    AddInputField("Delimiter", string(imp.delimiter), 4, nil, func (text string) {
+      r, _ := utf8.DecodeRuneInString(text)
+      if text == `\t` {
+        r = '\t'
+      }
+
+      if r != utf8.RuneError && r != imp.delimiter {
+        imp.delimiter = r
+        d.Reload()
+      }
+    }).
+    AddDropDown("NULL", csvNullOptions, imp.null, func (option string, index int) {
+      imp.null = index
+    })
+
+  for _, column := range imp.columns {
+    d.form.AddDropDown(column, []string{}, 0, nil)
+  }
+
+  d.form.
+    AddButton("Import", func () {
+      d.Import(c)
+    }).
+    AddButton("Cancel", func () {
+      if d.cancel != nil {
+        d.cancel()
+        return
+      }
+      d.Close(c)
+    })
+
+  d.form.
+    SetFieldBackgroundColor(tcell.NewRGBColor(100, 50, 200)).
+    SetButtonBackgroundColor(tcell.NewRGBColor(100, 50, 200)).
+    SetButtonsAlign(tview.AlignRight)
+
+  handler := GetFormKeyHandler(c.app, d.form, d.form.GetFormItemCount(), 2)
+  d.form.
+    SetInputCapture(func (event *tcell.EventKey) *tcell.EventKey {
+      if event.Key() == tcell.KeyESC && d.cancel == nil {
+        d.Close(c)
+        return nil
+      }
+      return handler(event)
+    })
+
+	d.layout = tview.NewGrid().
+    SetBorders(true).
+		SetRows(1, CSV_PREVIEW_ROWS + 1, -1, 1).
+		SetColumns(-1).
+		AddItem(d.title,   0, 0, 1, 1, 0, 0, false).
+		AddItem(d.preview, 1, 0, 1, 1, 0, 0, false).
+		AddItem(d.form,    2, 0, 1, 1, 0, 0, true).
+		AddItem(d.msg,     3, 0, 1, 1, 0, 0, false)
+
+  d.Reload()
+
+  return d
+}
+
+// Reads the preview again and maps the columns from it
+func (d *ImportDialog) Reload() {
+  records, err := d.imp.Preview(CSV_PREVIEW_ROWS)
+  d.records = records
+
+  if err != nil {
+    d.msg.SetText("[red]" + tview.Escape(err.Error()) + "[white]")
+  } else {
+    d.msg.SetText(fmt.Sprintf("%d column(s) in the table.", len(d.imp.columns)))
+  }
+
+  first := []string{}
+  if len(records) > 0 {
+    first = records[0]
+  }
+
+  d.imp.AutoMap(first)
+
+  fields := []string{}
+  values := records
+
+  if d.imp.header && len(records) > 0 {
+    values = records[1:]
+    for _, name := range first {
+      fields = append(fields, tview.Escape(name))
+    }
+  } else {
+    for i := range first {
+      fields = append(fields, fmt.Sprintf("column %d", i + 1))
+    }
+  }
+
+  escaped := [][]string{}
+  for _, row := range values {
+    escapedRow := []string{}
+    for _, value := range row {
+      escapedRow = append(escapedRow, tview.Escape(value))
+    }
+    escaped = append(escaped, escapedRow)
+  }
+
+  TableSetData(d.preview, fields, escaped, true)
+
+  options := []string{"(skip)"}
+  for i, field := range fields {
+    options = append(options, fmt.Sprintf("%d: %s", i + 1, field))
+  }
+
+  for i := range d.imp.columns {
+    dropDown, ok := d.form.GetFormItem(3 + i).(*tview.DropDown)
+    if !ok {
+      continue
+    }
+
+    index := i
+    dropDown.SetOptions(options, func (option string, selected int) {
+      d.imp.mapping[index] = selected - 1
+    })
+    dropDown.SetCurrentOption(d.imp.mapping[i] + 1)
+  }
+}
+
+func (d *ImportDialog) Import(c *Context) {
+  if d.cancel != nil {
+    return
+  }
+
+  ctx, cancel := context.WithCancel(context.Background())
+  d.cancel = cancel
+
+  d.msg.SetText("Importing...")
+
+  go func () {
+    defer cancel()
+
+    rows, err := d.imp.Copy(ctx, c.db, func (rows int64) {
+      c.Enqueue(func () {
+        d.msg.SetText(fmt.Sprintf("%d rows copied...", rows))
+      })
+    })
+
+    c.Enqueue(func () {
+      d.cancel = nil
+
+      if err != nil {
+        d.msg.SetText("[red]Import rolled back: " + tview.Escape(err.Error()) + "[white]")
+        return
+      }
+
+      d.Close(c)
+      c.runPage.SetStatus(fmt.Sprintf("%d rows imported into %s.", rows, d.imp.name))
+    })
+  }()
+}
+
+func (d *ImportDialog) Close(c *Context) {
+  c.mainPages.RemovePage("Import")
+  c.runPage.SetCompType(EDITOR)
+  c.SetFocus(c.runPage.editor.tv)
+}
+
+func (d *ImportDialog) Layout() tview.Primitive {
+  return d.layout
+}
@@ -9,6 +9,8 @@ type InitPage struct {
 	msg    *tview.TextView
   form   *tview.Form
   layout *tview.Grid
+
+  defaults *DBInfo // connection that filled the form, gives the settings out of it
 }
 
 func NewInitPage(c *Context) *InitPage {
@@ -16,7 +18,7 @@ func NewInitPage(c *Context) *InitPage {
   config, err := ReadConfigFile()
 
   if err != nil {
-    config = &Config{[]Connection{}}
+    config = &Config{Connections: []Connection{}}
     if err.Error() == "json_error" {
       msg = "Invalid config file found."
     }
@@ -26,7 +28,7 @@ func NewInitPage(c *Context) *InitPage {
 
   info := DefaultDbInfo(c.config)
 
-  ip := &InitPage{}
+  ip := &InitPage{defaults: info}
 
 	ip.msg = tview.NewTextView().
     SetDynamicColors(true).
@@ -45,6 +47,7 @@ func NewInitPage(c *Context) *InitPage {
 		//AddPasswordField("Password", info.pass, 0, '*', nil).
 		AddInputField("Database", info.name, 0, nil, nil).
 		AddCheckbox("Enable SSL", info.ssl, nil).
+		AddCheckbox("Read only", info.readOnly, nil).
 		AddButton("Connect", func() {
 
       // Maybe create a proper treatment for the case in which
@@ -54,12 +57,30 @@ func NewInitPage(c *Context) *InitPage {
       }
 
       c.info = &DBInfo{
+        conn: ip.defaults.conn,
+
         host: GetFormInputValue(ip.form, 0),
         port: GetFormInputValue(ip.form, 1),
         user: GetFormInputValue(ip.form, 2),
         pass: GetFormInputValue(ip.form, 3),
         name: GetFormInputValue(ip.form, 4),
         ssl:  GetFormCheckValue(ip.form, 5),
+
+        statementTimeout: ip.defaults.statementTimeout,
+        lockTimeout:      ip.defaults.lockTimeout,
+        idleTimeout:      ip.defaults.idleTimeout,
+
+        protected: ip.defaults.protected,
+        readOnly:  GetFormCheckValue(ip.form, 6),
+      }
+
+      // The form doesn't point to the saved connection anymore, its settings
+      // are for another server. Read only stays, it is on the form.
+      if c.info.host != ip.defaults.host || c.info.port != ip.defaults.port ||
+        c.info.name != ip.defaults.name || c.info.user != ip.defaults.user {
+        c.info.conn = ""
+        c.info.statementTimeout, c.info.lockTimeout, c.info.idleTimeout = "", "", ""
+        c.info.protected = false
       }
 
       c.loading.SetTextView(ip.msg)
@@ -76,10 +97,36 @@ func NewInitPage(c *Context) *InitPage {
           return
         }
 
+        session, err := OpenSession(db)
+
+        if err != nil {
+          c.loading.Close()
+          c.Enqueue(func () {
+            ip.msg.SetText(err.Error())
+          })
+          return
+        }
+
         c.loading.Close()
 
         c.db = db
+        c.SetSession(session)
+        c.monitor = NewMonitor(c.info.ConnString(),
+          func (n Notification) {
+            c.Enqueue(func () { c.notifyPage.Add(n) })
+          },
+          func (event string) {
+            c.Enqueue(func () { c.notifyPage.SetEvent(event) })
+          })
+
         c.Enqueue(func () {
+          if c.watch != nil {
+            c.watch.Close()
+          }
+          c.WatchConnection()
+
+          c.RefreshMenuBar()
+          c.runPage.UpdateTxState(c)
           c.mainPages.SwitchToPage("SQL")
         })
 
@@ -100,7 +147,7 @@ func NewInitPage(c *Context) *InitPage {
     SetTitle(" Connection ").
     SetTitleAlign(tview.AlignLeft)
 
-  handler := GetFormKeyHandler(c.app, ip.form, 6, 2)
+  handler := GetFormKeyHandler(c.app, ip.form, 7, 2)
 
   ip.form.
     SetInputCapture(handler)
@@ -126,22 +173,17 @@ func (ip *InitPage) UpdateForm(c *Connection) {
   SetFormInputValue(ip.form, 3, c.Pass)
   SetFormInputValue(ip.form, 4, c.Db)
   SetFormCheckValue(ip.form, 5, c.Ssl)
+  SetFormCheckValue(ip.form, 6, c.ReadOnly)
+
+  ip.defaults = c.DbInfo()
 }
 
 func DefaultDbInfo(c *Config) *DBInfo {
-  info := &DBInfo{ user: "postgres", host: "localhost", port: "5432" }
-
   for i := 0; i < len(c.Connections); i++ {
     if c.Connections[i].IsDefault {
-      info.user = c.Connections[i].User
-      info.name = c.Connections[i].Db
-      info.host = c.Connections[i].Host
-      info.port = c.Connections[i].Port
-      info.pass = c.Connections[i].Pass
-      info.ssl = c.Connections[i].Ssl
-      break
+      return c.Connections[i].DbInfo()
     }
   }
 
-  return info
+  return &DBInfo{ user: "postgres", host: "localhost", port: "5432" }
 }
@@ -0,0 +1,281 @@
+package main
+
+import (
+  "context"
+  "fmt"
+  "os"
+  "sync"
+  "time"
+)
+
+type JobState byte
+
+const (
+  JOB_RUNNING JobState = iota
+  JOB_DONE
+  JOB_FAILED
+  JOB_CANCELLED
+)
+
+func (js JobState) String() string {
+  switch js {
+  case JOB_RUNNING:
+    return "running"
+  case JOB_DONE:
+    return "done"
+  case JOB_FAILED:
+    return "failed"
+  case JOB_CANCELLED:
+    return "cancelled"
+  }
+  return "??"
+}
+
+// Jobs running longer than this notify the terminal when they finish
+const JOB_NOTIFY_AFTER = 5 * time.Second
+
+// Script run in the background, on a session of its own
+type Job struct {
+  id     int
+  script string
+
+  state   JobState
+  results []*StatementResult
+  rows    int64
+
+  start, end time.Time
+  cancel     context.CancelFunc
+}
+
+func (j *Job) Elapsed() time.Duration {
+  if j.state == JOB_RUNNING {
+    return time.Now().Sub(j.start)
+  }
+  return j.end.Sub(j.start)
+}
+
+type JobList struct {
+  mutex sync.Mutex
+  jobs  []*Job
+  next  int
+
+  ticking  bool
+  onChange func() // called from the UI goroutine whenever the list should be redrawn
+}
+
+func NewJobList() *JobList {
+  return &JobList{jobs: []*Job{}, next: 1, onChange: func() {}}
+}
+
+func (jl *JobList) SetChangeCb(cb func()) {
+  jl.onChange = cb
+}
+
+// Snapshot of the jobs, newest first
+func (jl *JobList) Jobs() []Job {
+  jl.mutex.Lock()
+  defer jl.mutex.Unlock()
+
+  jobs := []Job{}
+  for i := len(jl.jobs) - 1; i >= 0; i-- {
+    jobs = append(jobs, *jl.jobs[i])
+  }
+
+  return jobs
+}
+
+func (jl *JobList) Get(id int) *Job {
+  jl.mutex.Lock()
+  defer jl.mutex.Unlock()
+
+  for _, job := range jl.jobs {
+    if job.id == id {
+      return job
+    }
+  }
+  return nil
+}
+
+func (jl *JobList) Cancel(id int) bool {
+  jl.mutex.Lock()
+  defer jl.mutex.Unlock()
+
+  for _, job := range jl.jobs {
+    if job.id == id && job.cancel != nil {
+      job.cancel()
+      return true
+    }
+  }
+  return false
+}
+
+// Removes a job that isn't running
+func (jl *JobList) Remove(id int) bool {
+  jl.mutex.Lock()
+  defer jl.mutex.Unlock()
+
+  for i, job := range jl.jobs {
+    if job.id == id && job.state != JOB_RUNNING {
+      jl.jobs = append(jl.jobs[:i], jl.jobs[i + 1:]...)
+      return true
+    }
+  }
+  return false
+}
+
+func (jl *JobList) Running() int {
+  jl.mutex.Lock()
+  defer jl.mutex.Unlock()
+
+  running := 0
+  for _, job := range jl.jobs {
+    if job.state == JOB_RUNNING {
+      running++
+    }
+  }
+  return running
+}
+
+func (jl *JobList) Start(c *Context, script string, statements []string, args [][]interface{}) *Job {
+  ctx, cancel := context.WithCancel(context.Background())
+
+  jl.mutex.Lock()
+  job := &Job{id: jl.next, script: script, start: time.Now(), cancel: cancel}
+  jl.next++
+  jl.jobs = append(jl.jobs, job)
+  jl.mutex.Unlock()
+
+  jl.tick(c)
+
+  go func () {
+    defer cancel()
+
+    results, state := jl.run(ctx, c, job, statements, args)
+
+    rows := int64(0)
+    for _, sr := range results {
+      rows += sr.Rows()
+    }
+
+    jl.mutex.Lock()
+    job.results, job.state, job.rows = results, state, rows
+    job.end = time.Now()
+    job.cancel = nil
+    jl.mutex.Unlock()
+
+    c.Enqueue(func () {
+      jl.onChange()
+      jl.finished(c, job)
+    })
+  }()
+
+  return job
+}
+
+// Rows the job has got so far, shown while it runs
+func (jl *JobList) setRows(job *Job, rows int64) {
+  jl.mutex.Lock()
+  job.rows = rows
+  jl.mutex.Unlock()
+}
+
+func (jl *JobList) run(ctx context.Context, c *Context, job *Job, statements []string, args [][]interface{}) ([]*StatementResult, JobState) {
+  results := []*StatementResult{}
+
+  session, err := OpenSession(c.db)
+  if err != nil {
+    sr := &StatementResult{query: statements[0], result: ErrorResult(err)}
+    return append(results, sr), JOB_FAILED
+  }
+
+  defer func () {
+    // The connection goes back to the pool, without any open transaction
+    session.CloseCursor()
+    if session.State() != TX_IDLE {
+      session.Rollback(context.Background())
+    }
+    session.Close()
+  }()
+
+  stopOnError := ScriptStopOnError(job.script, c.runPage.stopOnError)
+  state := JOB_DONE
+  rows := int64(0) // of the statements already run
+
+  for i, statement := range statements {
+    sr := c.runPage.RunStatement(ctx, session, statement, args[i])
+    jl.setRows(job, rows + sr.Rows())
+
+    // The whole result is fetched, the session doesn't outlive the job
+    for sr.cursor != nil && !sr.cursor.Done() && sr.result.err == nil {
+      result := sr.cursor.Fetch(ctx)
+      sr.notices = append(sr.notices, session.TakeNotices()...)
+
+      if result.err != nil {
+        sr.result.err = result.err
+        break
+      }
+
+      sr.result.values = append(sr.result.values, result.values...)
+      sr.result.nulls = append(sr.result.nulls, result.nulls...)
+      jl.setRows(job, rows + sr.Rows())
+    }
+
+    sr.cancelled = ctx.Err() == context.Canceled
+    results = append(results, sr)
+    rows += sr.Rows()
+
+    c.history.Add(NewHistoryEntry(c.info, sr))
+
+    if sr.cancelled {
+      return results, JOB_CANCELLED
+    }
+
+    if sr.result.err != nil {
+      state = JOB_FAILED
+      if stopOnError {
+        break
+      }
+    }
+  }
+
+  return results, state
+}
+
+// Redraws the list every second while a job is running, for the elapsed times
+func (jl *JobList) tick(c *Context) {
+  if jl.ticking {
+    return
+  }
+  jl.ticking = true
+
+  go func () {
+    for jl.Running() > 0 {
+      c.Enqueue(jl.onChange)
+      time.Sleep(time.Second)
+    }
+
+    c.Enqueue(func () {
+      jl.ticking = false
+      jl.onChange()
+    })
+  }()
+}
+
+// Tells the user a job finished. Long jobs ring the terminal bell and send an
+// OSC 9 notification, unless the Jobs page is already being looked at.
+func (jl *JobList) finished(c *Context, job *Job) {
+  message := fmt.Sprintf("Job %d %s after %s, %d rows.",
+    job.id, job.state.String(), FormatElapsed(job.Elapsed()), job.rows)
+
+  c.runPage.SetStatus(message)
+
+  if c.selectedMenu == JOBS_MENU || job.Elapsed() < JOB_NOTIFY_AFTER {
+    return
+  }
+
+  // The screen is suspended while they are written, so they can't end up in
+  // the middle of a draw
+  go c.app.Suspend(func () {
+    fmt.Fprint(os.Stdout, "\a\x1b]9;postdigress: " + message + "\x07")
+  })
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+  "github.com/gdamore/tcell"
+  "fmt"
+  "strings"
+)
+
+var jobsFields = []string{"Job", "State", "Elapsed", "Rows", "Query"}
+
+type JobsPage struct {
+  title *tview.TextView
+  table *tview.Table
+
+  layout *tview.Grid
+
+  focusedType ComponentType
+
+  ids []int // job of each table row
+}
+
+func NewJobsPage(c *Context) *JobsPage {
+  jp := &JobsPage{ids: []int{}}
+
+  jp.focusedType = MENU
+
+  jp.title = tview.NewTextView().
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetWrap(false).
+    SetText(" Select a query and press Ctrl-B to run it as a job. " +
+      "[yellow]o[white]: open result, [yellow]x[white]: cancel, [yellow]d[white]: remove")
+
+  jp.table = tview.NewTable().
+		SetBorders(false).
+		SetSeparator(tview.Borders.Vertical)
+
+  jp.table.SetSelectable(true, false)
+
+	jp.layout = tview.NewGrid().
+		SetBorders(true).
+		SetRows(1, 1, -1).
+		SetColumns(-1).
+		AddItem(c.menuBar,  0, 0, 1, 1, 0, 0, false).
+		AddItem(jp.title,   1, 0, 1, 1, 0, 0, false).
+		AddItem(jp.table,   2, 0, 1, 1, 0, 0, true)
+
+  jp.layout.
+    SetInputCapture(func (event *tcell.EventKey) *tcell.EventKey {
+      if jp.focusedType == MENU {
+        c.HandleMenuKeyInput(event)
+        return event
+      }
+
+      id := jp.SelectedJob()
+
+      switch {
+      case event.Rune() == 'q':
+        jp.SetCompType(MENU)
+        c.FocusMenu()
+
+      case event.Rune() == 'o' || event.Key() == tcell.KeyEnter:
+        job := c.jobs.Get(id)
+        if job != nil && job.state != JOB_RUNNING {
+          jp.SetCompType(MENU)
+          c.runPage.ShowJob(c, job)
+        }
+
+      case event.Rune() == 'x':
+        c.jobs.Cancel(id)
+
+      case event.Rune() == 'd':
+        if c.jobs.Remove(id) {
+          jp.Render(c.jobs.Jobs())
+        }
+
+      default:
+        return event
+      }
+
+      return nil
+    })
+
+  jp.Render([]Job{})
+
+  return jp
+}
+
+func (jp *JobsPage) SetCompType(t ComponentType) {
+  jp.focusedType = t
+}
+
+func (jp *JobsPage) SelectedJob() int {
+  row, _ := jp.table.GetSelection()
+
+  if row < 1 || row > len(jp.ids) {
+    return 0
+  }
+  return jp.ids[row - 1]
+}
+
+func (jp *JobsPage) Render(jobs []Job) {
+  selected := jp.SelectedJob()
+
+  jp.ids = []int{}
+  values := [][]string{}
+
+  for _, job := range jobs {
+    query := strings.Join(strings.Fields(TrimStatement(job.script)), " ")
+
+    jp.ids = append(jp.ids, job.id)
+    values = append(values, []string{
+      fmt.Sprintf("%d", job.id),
+      job.state.String(),
+      FormatElapsed(job.Elapsed()),
+      fmt.Sprintf("%d", job.rows),
+      tview.Escape(StrClip(query, 200)),
+    })
+  }
+
+  TableSetData(jp.table, jobsFields, values, false)
+
+  for i, job := range jobs {
+    color := tcell.ColorWhite
+    switch job.state {
+    case JOB_RUNNING:
+      color = tcell.ColorYellow
+    case JOB_FAILED, JOB_CANCELLED:
+      color = tcell.ColorRed
+    }
+
+    jp.table.GetCell(i + 1, 1).SetTextColor(color)
+
+    if job.id == selected {
+      jp.table.Select(i + 1, 0)
+    }
+  }
+}
+
+func (jp *JobsPage) Layout() tview.Primitive {
+  return jp.layout
+}
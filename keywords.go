@@ -0,0 +1,162 @@
+package main
+
+import (
+  "database/sql"
+  "strings"
+  "sync"
+)
+
+// Keywords of Postgres 17, by category, like pg_get_keywords() gives them.
+// Reserved words can't be used as names without quotes, unreserved ones can.
+var reservedKeywords = `
+  all analyse analyze and any array as asc asymmetric both case cast check
+  collate column constraint create current_catalog current_date current_role
+  current_time current_timestamp current_user default deferrable desc distinct
+  do else end except false fetch for foreign from grant group having in
+  initially intersect into lateral leading limit localtime localtimestamp not
+  null offset on only or order placing primary references returning select
+  session_user some symmetric system_user table then to trailing true union
+  unique user using variadic when where window with`
+
+// Reserved, but they can be function or type names
+var typeFuncKeywords = `
+  authorization binary collation concurrently cross current_schema freeze full
+  ilike inner is isnull join left like natural notnull outer overlaps right
+  similar tablesample verbose`
+
+// Unreserved, but they can't be function or type names
+var colNameKeywords = `
+  between bigint bit boolean char character coalesce dec decimal exists extract
+  float greatest grouping inout int integer interval json json_array
+  json_arrayagg json_exists json_object json_objectagg json_query json_scalar
+  json_serialize json_table json_value least merge_action national nchar none
+  normalize nullif numeric out overlay position precision real row setof
+  smallint substring time timestamp treat trim values varchar xmlattributes
+  xmlconcat xmlelement xmlexists xmlforest xmlnamespaces xmlparse xmlpi xmlroot
+  xmlserialize xmltable`
+
+var unreservedKeywords = `
+  abort absent absolute access action add admin after aggregate also alter
+  always asensitive assertion assignment at atomic attach attribute backward
+  before begin breadth by cache call called cascade cascaded catalog chain
+  characteristics checkpoint class close cluster columns comment comments commit
+  committed compression conditional configuration conflict connection
+  constraints content continue conversion copy cost csv cube current cursor
+  cycle data database day deallocate declare defaults deferred definer delete
+  delimiter delimiters depends depth detach dictionary disable discard document
+  domain double drop each empty enable encoding encrypted enum error escape
+  event exclude excluding exclusive execute explain expression extension
+  external family filter finalize first following force format forward function
+  functions generated global granted groups handler header hold hour identity
+  if immediate immutable implicit import include including increment indent
+  index indexes inherit inherits inline input insensitive insert instead invoker
+  isolation keep key keys label language large last leakproof level listen load
+  local location lock locked logged mapping match matched materialized maxvalue
+  merge method minute minvalue mode month move name names nested new next nfc
+  nfd nfkc nfkd no normalized nothing notify nowait nulls object of off oids old
+  omit operator option options ordinality others over overriding owned owner
+  parallel parameter parser partial partition passing password path period plan
+  plans policy preceding prepare prepared preserve prior privileges procedural
+  procedure procedures program publication quote quotes range read reassign
+  recursive ref referencing refresh reindex relative release rename repeatable
+  replace replica reset restart restrict return returns revoke role rollback
+  rollup routine routines rows rule savepoint scalar schema schemas scroll
+  search second security sequence sequences serializable server session set
+  sets share show simple skip snapshot source sql stable standalone start
+  statement statistics stdin stdout storage stored strict string strip
+  subscription support sysid system tables tablespace target temp template
+  temporary text ties transaction transform trigger truncate trusted type types
+  uescape unbounded uncommitted unconditional unencrypted unknown unlisten
+  unlogged until update vacuum valid validate validator value varying version
+  view views volatile whitespace within without work wrapper write xml year yes
+  zone`
+
+// Builtin types, with their aliases. They win over the keyword categories,
+// int and text are keywords too. Geometric types and name are left out,
+// they are common column names.
+var builtinTypes = `
+  bigint bigserial bit bool boolean bpchar bytea char character cidr date
+  daterange datemultirange decimal double float float4 float8 inet int int2
+  int4 int4range int8 int8range integer interval json jsonb jsonpath macaddr
+  macaddr8 money numeric numrange oid pg_lsn precision real record regclass
+  regproc regtype serial serial2 serial4 serial8 smallint smallserial text time
+  timestamp timestamptz timetz tsquery tsrange tstzrange tsvector uuid varbit
+  varchar varying void xml`
+
+// The table is replaced by RefreshKeywords while other goroutines tokenize
+var identTypesMutex sync.RWMutex
+
+var identTypes = BuildIdentTypes(map[string]string{
+  "R": reservedKeywords,
+  "T": typeFuncKeywords,
+  "C": colNameKeywords,
+  "U": unreservedKeywords,
+})
+
+// Token type of each word, from its pg_get_keywords() category code
+func BuildIdentTypes(keywords map[string]string) map[string]TokenType {
+  types := map[string]TokenType{}
+
+  for code, words := range keywords {
+    ttype := UNRESERVED
+    if code == "R" || code == "T" {
+      ttype = KEYWORD
+    }
+
+    for _, word := range strings.Fields(words) {
+      types[word] = ttype
+    }
+  }
+
+  for _, word := range strings.Fields(builtinTypes) {
+    types[word] = TYPE
+  }
+
+  return types
+}
+
+func GetIdentType(name string) TokenType {
+  identTypesMutex.RLock()
+  defer identTypesMutex.RUnlock()
+
+  if ttype, ok := identTypes[strings.ToLower(name)]; ok {
+    return ttype
+  }
+  return IDENT
+}
+
+// Replaces the keyword table by the one of the connected server
+func RefreshKeywords(db *sql.DB) (int, error) {
+  rows, err := db.Query("SELECT word, catcode FROM pg_catalog.pg_get_keywords()")
+
+  if err != nil {
+    return 0, err
+  }
+  defer rows.Close()
+
+  keywords := map[string]string{}
+  count := 0
+
+  for rows.Next() {
+    var word, code string
+
+    if err := rows.Scan(&word, &code); err != nil {
+      return 0, err
+    }
+
+    keywords[code] += " " + word
+    count++
+  }
+
+  if err := rows.Err(); err != nil {
+    return 0, err
+  }
+
+  types := BuildIdentTypes(keywords)
+
+  identTypesMutex.Lock()
+  identTypes = types
+  identTypesMutex.Unlock()
+
+  return count, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+  "errors"
+  "sort"
+  "time"
+
+	"github.com/lib/pq"
+)
+
+// Notification received on a listened channel
+type Notification struct {
+  time    time.Time
+  channel string
+  payload string
+  pid     int
+}
+
+// Keeps a dedicated connection listening to the channels asked by the user.
+// The connection is only opened by the first listen.
+type Monitor struct {
+  connStr  string
+  listener *pq.Listener
+  channels map[string]bool
+
+  onNotify func(Notification)
+  onEvent  func(string)
+}
+
+func NewMonitor(connStr string, onNotify func(Notification), onEvent func(string)) *Monitor {
+  return &Monitor{
+    connStr: connStr,
+    channels: map[string]bool{},
+    onNotify: onNotify,
+    onEvent: onEvent,
+  }
+}
+
+func (m *Monitor) start() {
+  m.listener = pq.NewListener(m.connStr, time.Second, time.Minute,
+    func (event pq.ListenerEventType, err error) {
+      switch event {
+      case pq.ListenerEventDisconnected:
+        m.onEvent("Listener disconnected: " + err.Error())
+      case pq.ListenerEventReconnected:
+        m.onEvent("Listener reconnected.")
+      case pq.ListenerEventConnectionAttemptFailed:
+        m.onEvent("Listener failed to connect: " + err.Error())
+      }
+    })
+
+  go m.receive(m.listener)
+}
+
+func (m *Monitor) receive(listener *pq.Listener) {
+  for {
+    select {
+    case n, ok := <-listener.Notify:
+      if !ok {
+        return
+      }
+
+      // A nil notification is sent after a reconnection, when some
+      // notifications could have been lost
+      if n == nil {
+        continue
+      }
+
+      m.onNotify(Notification{
+        time: time.Now(),
+        channel: n.Channel,
+        payload: n.Extra,
+        pid: n.BePid,
+      })
+
+    case <-time.After(90 * time.Second):
+      go listener.Ping()
+    }
+  }
+}
+
+func (m *Monitor) Listen(channel string) error {
+  if m.listener == nil {
+    m.start()
+  }
+
+  err := m.listener.Listen(channel)
+  if err != nil && err != pq.ErrChannelAlreadyOpen {
+    return err
+  }
+
+  m.channels[channel] = true
+  return nil
+}
+
+func (m *Monitor) Unlisten(channel string) error {
+  if !m.channels[channel] {
+    return errors.New("Not listening to " + channel + ".")
+  }
+
+  err := m.listener.Unlisten(channel)
+  if err != nil && err != pq.ErrChannelNotOpen {
+    return err
+  }
+
+  delete(m.channels, channel)
+  return nil
+}
+
+func (m *Monitor) Channels() []string {
+  channels := []string{}
+  for channel := range m.channels {
+    channels = append(channels, channel)
+  }
+
+  sort.Strings(channels)
+  return channels
+}
+
+func (m *Monitor) Close() {
+  if m.listener != nil {
+    m.listener.Close()
+    m.listener = nil
+  }
+}
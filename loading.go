@@ -43,6 +43,8 @@ func (l *Loading) Init(app *tview.Application) {
   l.waiting = true
 
   ticker := time.NewTicker(time.Millisecond)
+  defer ticker.Stop()
+
   lastTime := time.Now()
   startTime := time.Now()
 
@@ -61,12 +63,14 @@ func (l *Loading) Init(app *tview.Application) {
         }
       }
 
+      elapsed := FormatElapsed(currTime.Sub(startTime))
+
       app.QueueUpdateDraw(func() {
         l.tv.Clear()
       })
 
       app.QueueUpdateDraw(func() {
-        fmt.Fprint(l.tv, InsertTag("|  Loading  |", l.pos))
+        fmt.Fprint(l.tv, InsertTag("|  Loading  |", l.pos) + " " + elapsed)
         l.tv.Highlight("0")
       })
 
@@ -82,7 +86,10 @@ func (l *Loading) Init(app *tview.Application) {
         fmt.Fprint(l.tv, "Waiting for too long! Something wrong might have happened!.")
       })
 
-      l.Close()
+      // Closing from here would wait on ourselves, so just stop the animation
+      // and let the owner's Close return right away.
+      l.waiting = false
+      return
     }
   }
   l.closed <- true
@@ -94,3 +101,7 @@ func (l *Loading) Close() {
     <-l.closed
   }
 }
+
+func FormatElapsed(d time.Duration) string {
+  return d.Truncate(100 * time.Millisecond).String()
+}
@@ -1,11 +1,8 @@
 package main
 
-//TODO: implement export table
-
 import (
 	"github.com/rivo/tview"
   "github.com/gdamore/tcell"
-  "fmt"
   "sort"
 )
 
@@ -21,15 +18,30 @@ func main() {
     mainPages: mainPages,
     menuBar: menuBar,
     loading: NewLoading(nil),
+    jobs: NewJobList(),
+    history: OpenQueryHistory(),
   }
 
   initPage   := NewInitPage(context)
   runPage    := NewRunPage(context)
   structPage := NewStructPage(context)
+  planPage   := NewPlanPage(context)
+  notifyPage := NewNotifyPage(context)
+  jobsPage   := NewJobsPage(context)
+  historyPage := NewHistoryPage(context)
 
   context.initPage = initPage
   context.runPage = runPage
   context.structPage = structPage
+  context.planPage = planPage
+  context.notifyPage = notifyPage
+  context.jobsPage = jobsPage
+  context.historyPage = historyPage
+
+  context.jobs.SetChangeCb(func () {
+    jobsPage.Render(context.jobs.Jobs())
+    context.RefreshMenuBar()
+  })
 
   connPage := NewConnPage(context)
 
@@ -38,7 +50,11 @@ func main() {
 
   sqlPages.
     AddPage("0", runPage.Layout(), true, false).
-    AddPage("1", structPage.Layout(), true, false)
+    AddPage("1", structPage.Layout(), true, false).
+    AddPage("3", planPage.Layout(), true, false).
+    AddPage("4", notifyPage.Layout(), true, false).
+    AddPage("5", jobsPage.Layout(), true, false).
+    AddPage("6", historyPage.Layout(), true, false)
 
 	menuBar.
 		SetDynamicColors(true).
@@ -48,6 +64,10 @@ func main() {
 			sqlPages.SwitchToPage(added[0])
       context.FocusMenu()
 
+      if added[0] == "6" {
+        historyPage.Refresh(context)
+      }
+
       if added[0] == "1" {
         context.loading.SetTextView(structPage.dbSelect)
         go context.loading.Init(context.app)
@@ -69,6 +89,7 @@ func main() {
           context.Enqueue(func () {
             structPage.SetDBTitleName(context.info.name)
             structPage.SetTables(tables)
+            structPage.SelectPending(context)
           })
 
         }()
@@ -76,7 +97,7 @@ func main() {
 
 		})
 
-  fmt.Fprint(menuBar, ` ["0"][yellow] [::bu]E[::-]xecute [white][""] | ["1"][yellow] [::bu]S[::-]tructure [white][""] | ["2"][yellow] [::bu]Q[::-]uit [white][""]`)
+  context.RefreshMenuBar()
 
   menuBar.Highlight("0")
   context.selectedMenu = RUN_MENU
@@ -85,7 +106,7 @@ func main() {
 
   app.SetInputCapture(func (event *tcell.EventKey) *tcell.EventKey {
     if event.Key() == tcell.KeyCtrlC {
-      context.Finish()
+      context.Quit()
       return nil
     }
     return event
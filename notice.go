@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+  "github.com/gdamore/tcell"
+  "fmt"
+  "time"
+
+	"github.com/lib/pq"
+)
+
+// Message sent by the server while a statement runs (RAISE NOTICE, warnings...)
+type Notice struct {
+  severity string
+  code     string
+  message  string
+  detail   string
+  hint     string
+  where    string
+  time     time.Time
+}
+
+func NewNotice(err *pq.Error) Notice {
+  return Notice{
+    severity: err.Severity,
+    code:     string(err.Code),
+    message:  err.Message,
+    detail:   err.Detail,
+    hint:     err.Hint,
+    where:    err.Where,
+    time:     time.Now(),
+  }
+}
+
+func (n Notice) Color() tcell.Color {
+  switch n.severity {
+  case "WARNING":
+    return tcell.ColorOrange
+  case "NOTICE", "INFO":
+    return tcell.ColorWhite
+  }
+  return tcell.ColorGray // DEBUG and LOG
+}
+
+var messagesFields = []string{"#", "Time", "Statement", "Severity", "Code", "Message", "Detail", "Hint", "Where"}
+
+// Shows the notices of every statement of a script, in the order they arrived
+func TableSetMessages(table *tview.Table, results []*StatementResult) {
+  table.Clear()
+  table.SetFixed(1, 1)
+
+  for j, name := range messagesFields {
+    table.SetCell(0, j,
+      tview.NewTableCell(" " + name + " ").
+        SetTextColor(tcell.ColorYellow).
+        SetAlign(tview.AlignCenter))
+  }
+
+  row := 1
+  for i, sr := range results {
+    for _, n := range sr.notices {
+      values := []string{
+        n.time.Format("15:04:05.000"),
+        fmt.Sprintf("%d %s", i + 1, sr.Title()),
+        n.severity, n.code, n.message, n.detail, n.hint, n.where,
+      }
+
+      table.SetCell(row, 0,
+        tview.NewTableCell(fmt.Sprintf("%d", row)).
+          SetTextColor(tcell.ColorYellow).
+          SetAlign(tview.AlignCenter))
+
+      for j, value := range values {
+        table.SetCell(row, j + 1,
+          tview.NewTableCell(tview.Escape(value)).
+            SetTextColor(n.Color()).
+            SetMaxWidth(80))
+      }
+
+      row++
+    }
+  }
+}
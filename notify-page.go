@@ -0,0 +1,122 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+  "github.com/gdamore/tcell"
+  "fmt"
+  "strings"
+)
+
+var notificationsFields = []string{"Time", "Channel", "Payload", "PID"}
+
+// Incoming notifications kept by the page
+const MAX_NOTIFICATIONS = 1000
+
+type NotifyPage struct {
+  title *tview.TextView
+  table *tview.Table
+
+  layout *tview.Grid
+
+  focusedType ComponentType
+
+  notifications []Notification
+}
+
+func NewNotifyPage(c *Context) *NotifyPage {
+  np := &NotifyPage{}
+
+  np.focusedType = MENU
+  np.notifications = []Notification{}
+
+  np.title = tview.NewTextView().
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetWrap(false)
+
+  np.table = tview.NewTable().
+		SetBorders(false).
+		SetSeparator(tview.Borders.Vertical)
+
+  np.table.SetSelectable(true, false)
+
+  np.SetChannels([]string{})
+  np.Render()
+
+	np.layout = tview.NewGrid().
+		SetBorders(true).
+		SetRows(1, 1, -1).
+		SetColumns(-1).
+		AddItem(c.menuBar,  0, 0, 1, 1, 0, 0, false).
+		AddItem(np.title,   1, 0, 1, 1, 0, 0, false).
+		AddItem(np.table,   2, 0, 1, 1, 0, 0, true)
+
+  np.layout.
+    SetInputCapture(func (event *tcell.EventKey) *tcell.EventKey {
+      if np.focusedType == MENU {
+        c.HandleMenuKeyInput(event)
+
+      } else if event.Rune() == 'q' {
+        np.SetCompType(MENU)
+        c.FocusMenu()
+        return nil
+
+      } else if event.Rune() == 'c' {
+        np.notifications = []Notification{}
+        np.Render()
+        return nil
+      }
+
+      return event
+    })
+
+  return np
+}
+
+func (np *NotifyPage) SetCompType(t ComponentType) {
+  np.focusedType = t
+}
+
+func (np *NotifyPage) SetChannels(channels []string) {
+  if len(channels) == 0 {
+    np.title.SetText(" Not listening, use [yellow]listen <channel>[white] to start.")
+    return
+  }
+
+  np.title.SetText(" Listening: " + tview.Escape(strings.Join(channels, ", ")))
+}
+
+func (np *NotifyPage) SetEvent(event string) {
+  np.title.SetText(" " + tview.Escape(event))
+}
+
+func (np *NotifyPage) Add(n Notification) {
+  np.notifications = append(np.notifications, n)
+
+  if len(np.notifications) > MAX_NOTIFICATIONS {
+    np.notifications = np.notifications[len(np.notifications) - MAX_NOTIFICATIONS:]
+  }
+
+  np.Render()
+}
+
+// Newest notifications come first
+func (np *NotifyPage) Render() {
+  values := [][]string{}
+
+  for i := len(np.notifications) - 1; i >= 0; i-- {
+    n := np.notifications[i]
+    values = append(values, []string{
+      n.time.Format("2006-01-02 15:04:05.000"),
+      tview.Escape(n.channel),
+      tview.Escape(n.payload),
+      fmt.Sprintf("%d", n.pid),
+    })
+  }
+
+  TableSetData(np.table, notificationsFields, values, true)
+}
+
+func (np *NotifyPage) Layout() tview.Primitive {
+  return np.layout
+}
@@ -0,0 +1,521 @@
+package main
+
+import (
+  "strings"
+)
+
+// Table, view or function named by a FROM, JOIN, INTO, UPDATE or USING
+type TableRef struct {
+  name  string // as written, can be qualified
+  alias string
+
+  cte      bool // names a CTE of the query
+  function bool // set returning function, like generate_series(1, 10)
+
+  start, end int // rune positions of the name in the statement
+}
+
+type SelectItem struct {
+  expr  string
+  alias string
+}
+
+// What could be read of a SELECT, INSERT, UPDATE, DELETE or WITH. Half written
+// statements give what comes before the point where they stop making sense.
+type Query struct {
+  verb    string // the one after the CTEs, the first word for other statements
+  ctes    []string
+  tables  []TableRef
+  columns []SelectItem // select list, or the column list of an insert
+  where   bool
+
+  children []*Query // CTEs and subqueries
+}
+
+// Words that end a select list or a FROM clause
+var clauseWords = []string{
+  "into", "from", "where", "group", "having", "window", "order", "limit",
+  "offset", "fetch", "for", "union", "intersect", "except", "returning",
+}
+
+var joinWords = []string{"natural", "left", "right", "full", "inner", "cross", "join", "on", "using"}
+
+type parser struct {
+  wordReader
+}
+
+func ParseQuery(statement string) *Query {
+  tokens, input := Tokenize(statement)
+
+  p := &parser{wordReader{tokens: tokens, input: input}}
+  q := p.statement()
+  q.markCtes([]string{})
+
+  return q
+}
+
+func (p *parser) is(words ...string) bool {
+  word := p.peek()
+
+  for _, w := range words {
+    if word == w {
+      return true
+    }
+  }
+  return false
+}
+
+// End of the statement or of the parentheses around it
+func (p *parser) stop() bool {
+  return p.pos >= len(p.tokens) || p.punct(')') || p.punct(';')
+}
+
+func (p *parser) skipPunct(c rune) bool {
+  if p.punct(c) {
+    p.pos++
+    return true
+  }
+  return false
+}
+
+// Tells if the current token can start a name, reserved keywords can't
+func (p *parser) isName() bool {
+  if p.pos >= len(p.tokens) {
+    return false
+  }
+
+  switch p.tokens[p.pos].ttype {
+  case IDENT, QUOTED_IDENT, UNRESERVED, TYPE:
+    return true
+  }
+  return false
+}
+
+// Alias after a table or a select item, without AS only plain names count,
+// so the keyword after them isn't taken for one
+func (p *parser) alias() string {
+  if p.skip("as") {
+    if p.isName() {
+      p.pos++
+      return p.tokens[p.pos - 1].Text(p.input)
+    }
+    return ""
+  }
+
+  if p.pos < len(p.tokens) && (p.tokens[p.pos].Is(IDENT) || p.tokens[p.pos].Is(QUOTED_IDENT)) {
+    p.pos++
+    return p.tokens[p.pos - 1].Text(p.input)
+  }
+
+  return ""
+}
+
+func (p *parser) statement() *Query {
+  q := &Query{ctes: []string{}, tables: []TableRef{}, columns: []SelectItem{}, children: []*Query{}}
+
+  if p.skip("with") {
+    p.skip("recursive")
+
+    for p.isName() {
+      q.ctes = append(q.ctes, UnquoteIdent(p.tokens[p.pos].Text(p.input)))
+      p.pos++
+
+      if p.punct('(') {
+        p.parens(q)
+      }
+
+      p.skip("as")
+      p.skip("not")
+      p.skip("materialized")
+
+      // The body can be an INSERT, UPDATE or DELETE too
+      if p.skipPunct('(') {
+        q.children = append(q.children, p.statement())
+        p.skipPunct(')')
+      }
+
+      if !p.skipPunct(',') {
+        break
+      }
+    }
+  }
+
+  q.verb = p.peek()
+
+  switch q.verb {
+  case "select":
+    p.pos++
+    p.selectBody(q)
+  case "insert":
+    p.pos++
+    p.insert(q)
+  case "update":
+    p.pos++
+    p.update(q)
+  case "delete":
+    p.pos++
+    p.delete(q)
+  case "table":
+    p.pos++
+    p.fromItem(q)
+  }
+
+  p.expr(q)
+
+  return q
+}
+
+// Skips an expression up to one of the words, keeping its subqueries
+func (p *parser) expr(q *Query, stops ...string) {
+  for !p.stop() {
+    if p.punct('(') {
+      p.parens(q)
+      continue
+    }
+
+    if p.is(stops...) {
+      return
+    }
+    p.pos++
+  }
+}
+
+// Reads a parenthesized expression or subquery, with its closing parenthesis
+func (p *parser) parens(q *Query) {
+  p.pos++
+
+  if p.is("select", "with", "values", "table") {
+    q.children = append(q.children, p.statement())
+  }
+
+  p.expr(q)
+  p.skipPunct(')')
+}
+
+func (p *parser) selectBody(q *Query) {
+  p.skip("all")
+  if p.skip("distinct") && p.skip("on") && p.punct('(') {
+    p.parens(q)
+  }
+
+  p.selectList(q)
+
+  // The target of a SELECT INTO isn't read
+  if p.skip("into") {
+    p.skip("temporary", "temp", "unlogged")
+    p.skip("table")
+    p.name()
+  }
+
+  if p.skip("from") {
+    p.fromList(q)
+  }
+
+  if p.skip("where") {
+    q.where = true
+    p.expr(q, clauseWords...)
+  }
+
+  for !p.stop() {
+    if p.skip("union", "intersect", "except") {
+      p.skip("all", "distinct")
+
+      if p.skip("select") {
+        other := &Query{}
+        p.selectBody(other)
+
+        q.tables = append(q.tables, other.tables...)
+        q.children = append(q.children, other.children...)
+      }
+      continue
+    }
+
+    if p.is("returning") {
+      return
+    }
+
+    if p.punct('(') {
+      p.parens(q)
+    } else {
+      p.pos++
+    }
+  }
+}
+
+func (p *parser) selectList(q *Query) {
+  for !p.stop() && !p.is(clauseWords...) {
+    start := p.pos
+
+    for !p.stop() && !p.is(clauseWords...) && !p.punct(',') {
+      if p.punct('(') {
+        p.parens(q)
+      } else {
+        p.pos++
+      }
+    }
+
+    if p.pos > start {
+      q.columns = append(q.columns, p.selectItem(start, p.pos))
+    }
+
+    if !p.skipPunct(',') {
+      break
+    }
+  }
+}
+
+func (p *parser) selectItem(start, end int) SelectItem {
+  tokens := p.tokens[start:end]
+  item := SelectItem{}
+
+  if n := len(tokens); n >= 2 {
+    last, prev := tokens[n - 1], tokens[n - 2]
+
+    if LowerWord(p.input, prev) == "as" {
+      item.alias = last.Text(p.input)
+      tokens = tokens[:n - 2]
+    } else if (last.Is(IDENT) || last.Is(QUOTED_IDENT)) && !prev.Is(OPERATOR) && !prev.Is(CAST) &&
+      !(prev.Is(PUNCT) && p.input[prev.start] == '.') {
+      item.alias = last.Text(p.input)
+      tokens = tokens[:n - 1]
+    }
+  }
+
+  if len(tokens) > 0 {
+    last := tokens[len(tokens) - 1]
+    item.expr = string(p.input[tokens[0].start: last.start + last.size])
+  }
+
+  return item
+}
+
+func (p *parser) fromList(q *Query) {
+  for !p.stop() {
+    p.fromItem(q)
+
+    if p.skip("on") {
+      p.expr(q, append(joinWords, clauseWords...)...)
+    } else if p.skip("using") && p.punct('(') {
+      p.parens(q)
+    }
+
+    if p.skipPunct(',') {
+      continue
+    }
+
+    p.skip("natural")
+    p.skip("left", "right", "full", "inner", "cross")
+    p.skip("outer")
+
+    if !p.skip("join") {
+      return
+    }
+  }
+}
+
+func (p *parser) fromItem(q *Query) {
+  p.skip("lateral")
+
+  // A subquery, or joins inside parentheses
+  if p.punct('(') {
+    p.pos++
+
+    if p.is("select", "with", "values") {
+      q.children = append(q.children, p.statement())
+    } else {
+      p.fromList(q)
+    }
+
+    p.expr(q)
+    p.skipPunct(')')
+
+    if p.alias() != "" && p.punct('(') {
+      p.parens(q)
+    }
+    return
+  }
+
+  p.skip("only")
+
+  if !p.isName() {
+    return
+  }
+
+  ref := TableRef{start: p.tokens[p.pos].start}
+  ref.name = p.name()
+
+  last := p.tokens[p.pos - 1]
+  ref.end = last.start + last.size
+
+  if p.punct('(') {
+    ref.function = true
+    p.parens(q)
+  }
+
+  ref.alias = p.alias()
+
+  if ref.alias != "" && p.punct('(') {
+    p.parens(q)
+  }
+
+  q.tables = append(q.tables, ref)
+}
+
+func (p *parser) insert(q *Query) {
+  p.skip("into")
+
+  if !p.isName() {
+    return
+  }
+
+  // The parentheses after the table are its columns, not a function call
+  ref := TableRef{start: p.tokens[p.pos].start}
+  ref.name = p.name()
+
+  last := p.tokens[p.pos - 1]
+  ref.end = last.start + last.size
+
+  if p.skip("as") && p.isName() {
+    ref.alias = p.tokens[p.pos].Text(p.input)
+    p.pos++
+  }
+
+  q.tables = append(q.tables, ref)
+
+  if p.skipPunct('(') {
+    for p.isName() {
+      name := p.tokens[p.pos].Text(p.input)
+      q.columns = append(q.columns, SelectItem{expr: name})
+      p.pos++
+
+      if !p.skipPunct(',') {
+        break
+      }
+    }
+    p.expr(q)
+    p.skipPunct(')')
+  }
+
+  p.skip("overriding")
+
+  if p.is("select", "with", "values") {
+    q.children = append(q.children, p.statement())
+  }
+}
+
+func (p *parser) update(q *Query) {
+  p.fromItem(q)
+
+  if p.skip("set") {
+    p.expr(q, "from", "where", "returning")
+  }
+
+  if p.skip("from") {
+    p.fromList(q)
+  }
+
+  if p.skip("where") {
+    q.where = true
+  }
+}
+
+func (p *parser) delete(q *Query) {
+  p.skip("from")
+  p.fromItem(q)
+
+  if p.skip("using") {
+    p.fromList(q)
+  }
+
+  if p.skip("where") {
+    q.where = true
+  }
+}
+
+// Flags the references to the CTEs of the query and of the ones around it
+func (q *Query) markCtes(outer []string) {
+  names := append(append([]string{}, outer...), q.ctes...)
+
+  for i, ref := range q.tables {
+    if strings.Contains(ref.name, ".") {
+      continue
+    }
+
+    for _, name := range names {
+      if UnquoteIdent(ref.name) == name {
+        q.tables[i].cte = true
+      }
+    }
+  }
+
+  for _, child := range q.children {
+    child.markCtes(names)
+  }
+}
+
+// Tables of the query and its subqueries, leaving out CTEs and functions
+func (q *Query) AllTables() []TableRef {
+  tables := []TableRef{}
+
+  for _, ref := range q.tables {
+    if !ref.cte && !ref.function {
+      tables = append(tables, ref)
+    }
+  }
+
+  for _, child := range q.children {
+    tables = append(tables, child.AllTables()...)
+  }
+
+  return tables
+}
+
+// First table read by the query itself, then by its subqueries
+func (q *Query) FirstTable() string {
+  for _, ref := range q.tables {
+    if !ref.cte && !ref.function {
+      return ref.name
+    }
+  }
+
+  if tables := q.AllTables(); len(tables) > 0 {
+    return tables[0].name
+  }
+
+  return ""
+}
+
+// Table whose name or alias is at the position
+func (q *Query) TableAt(pos int) (TableRef, bool) {
+  for _, ref := range q.AllTables() {
+    if ref.start <= pos && pos <= ref.end {
+      return ref, true
+    }
+  }
+  return TableRef{}, false
+}
+
+// Name or word under a position of a statement, empty when there is none
+func WordAt(statement string, pos int) string {
+  tokens, input := Tokenize(statement)
+
+  for _, token := range tokens {
+    if token.start <= pos && pos <= token.start + token.size {
+      if token.Is(IDENT) || token.Is(QUOTED_IDENT) {
+        return token.Text(input)
+      }
+    }
+  }
+
+  return ""
+}
+
+// Table named by an alias, or by its own name
+func (q *Query) Resolve(alias string) (TableRef, bool) {
+  for _, ref := range q.AllTables() {
+    if ref.alias == alias || (ref.alias == "" && ref.name == alias) {
+      return ref, true
+    }
+  }
+  return TableRef{}, false
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+  "reflect"
+  "testing"
+)
+
+// Names and aliases of the tables of a query, like "s.t2 x"
+func tableNames(refs []TableRef) []string {
+  names := []string{}
+
+  for _, ref := range refs {
+    name := ref.name
+    if ref.alias != "" {
+      name += " " + ref.alias
+    }
+    names = append(names, name)
+  }
+
+  return names
+}
+
+func TestParseQuery(t *testing.T) {
+  tests := []struct {
+    query  string
+    verb   string
+    where  bool
+    tables []string // of the query and its subqueries, CTEs and functions aside
+    first  string
+  }{
+    {"select a from t1 x join s.t2 on x.id = t2.id where a > 1", "select", true, []string{"t1 x", "s.t2"}, "t1"},
+    {"with c as (select * from base) select * from c, generate_series(1, 3) g", "select", false, []string{"base"}, "base"},
+    {`select * from "My Table" m left join (select id from inner_t) sub using (id)`, "select", false,
+      []string{`"My Table" m`, "inner_t"}, `"My Table"`},
+    {"select (select max(x) from inner2) from outer1", "select", false, []string{"outer1", "inner2"}, "outer1"},
+    {"select * from a union select * from b", "select", false, []string{"a", "b"}, "a"},
+    {"insert into t (a, b) select x, y from src", "insert", false, []string{"t", "src"}, "t"},
+    {"update t set a = 1 from u where t.id = u.id", "update", true, []string{"t", "u"}, "t"},
+    {"delete from t using u where t.id = u.id", "delete", true, []string{"t", "u"}, "t"},
+    {"delete from t", "delete", false, []string{"t"}, "t"},
+    {"table t", "table", false, []string{"t"}, "t"},
+    {"select 1", "select", false, []string{}, ""},
+  }
+
+  for _, test := range tests {
+    q := ParseQuery(test.query)
+
+    if q.verb != test.verb || q.where != test.where {
+      t.Errorf("%q: got verb %q where %v, want %q %v", test.query, q.verb, q.where, test.verb, test.where)
+    }
+
+    if tables := tableNames(q.AllTables()); !reflect.DeepEqual(tables, test.tables) {
+      t.Errorf("%q: got tables %q, want %q", test.query, tables, test.tables)
+    }
+
+    if first := q.FirstTable(); first != test.first {
+      t.Errorf("%q: got first table %q, want %q", test.query, first, test.first)
+    }
+  }
+}
+
+func TestParseQueryCtes(t *testing.T) {
+  q := ParseQuery("with c as (select * from base) select * from c, generate_series(1, 3) g")
+
+  if !reflect.DeepEqual(q.ctes, []string{"c"}) {
+    t.Fatalf("got ctes %q", q.ctes)
+  }
+
+  if len(q.tables) != 2 || !q.tables[0].cte || !q.tables[1].function {
+    t.Errorf("got tables %+v, want the CTE c and a function", q.tables)
+  }
+
+  if len(q.children) != 1 || q.children[0].FirstTable() != "base" {
+    t.Errorf("the body of the CTE wasn't read: %+v", q.children)
+  }
+}
+
+func TestParseQueryColumns(t *testing.T) {
+  tests := []struct {
+    query   string
+    columns []SelectItem
+  }{
+    {"select a, b as bee, c cee, t.d from t", []SelectItem{{"a", ""}, {"b", "bee"}, {"c", "cee"}, {"t.d", ""}}},
+    {"select count(*)::int, x + 1 from t", []SelectItem{{"count(*)::int", ""}, {"x + 1", ""}}},
+    {"insert into t (a, b) values (1, 2)", []SelectItem{{"a", ""}, {"b", ""}}},
+  }
+
+  for _, test := range tests {
+    if columns := ParseQuery(test.query).columns; !reflect.DeepEqual(columns, test.columns) {
+      t.Errorf("%q: got %+v, want %+v", test.query, columns, test.columns)
+    }
+  }
+}
+
+func TestQueryResolve(t *testing.T) {
+  q := ParseQuery("select * from orders o join customers on o.customer = customers.id")
+
+  for alias, name := range map[string]string{"o": "orders", "customers": "customers"} {
+    ref, ok := q.Resolve(alias)
+    if !ok || ref.name != name {
+      t.Errorf("%q: got %q %v, want %q", alias, ref.name, ok, name)
+    }
+  }
+
+  if _, ok := q.Resolve("orders"); ok {
+    t.Errorf("a table with an alias was resolved by its name")
+  }
+}
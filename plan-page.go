@@ -0,0 +1,132 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+  "github.com/gdamore/tcell"
+  "fmt"
+  "strings"
+)
+
+type PlanPage struct {
+  title   *tview.TextView
+  tree    *tview.TreeView
+  details *tview.TextView
+
+  layout *tview.Grid
+
+  focusedType ComponentType
+
+  plan *Plan
+}
+
+func NewPlanPage(c *Context) *PlanPage {
+  pp := &PlanPage{}
+
+  pp.focusedType = MENU
+
+  pp.title = tview.NewTextView().
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetWrap(false)
+
+  pp.title.SetText(" No plan yet, select a query and press Ctrl-P (or Ctrl-A to analyze it).")
+
+  pp.tree = tview.NewTreeView()
+
+  pp.tree.SetSelectedFunc(func (node *tview.TreeNode) {
+    node.SetExpanded(!node.IsExpanded())
+  })
+
+  pp.tree.SetChangedFunc(func (node *tview.TreeNode) {
+    pp.ShowDetails(node)
+  })
+
+  pp.details = tview.NewTextView().
+		SetDynamicColors(false).
+		SetWrap(true)
+
+	pp.layout = tview.NewGrid().
+		SetBorders(true).
+		SetRows(1, 1, -3, -1).
+		SetColumns(-1).
+		AddItem(c.menuBar,   0, 0, 1, 1, 0, 0, false).
+		AddItem(pp.title,    1, 0, 1, 1, 0, 0, false).
+		AddItem(pp.tree,     2, 0, 1, 1, 0, 0, true).
+		AddItem(pp.details,  3, 0, 1, 1, 0, 0, false)
+
+  pp.layout.
+    SetInputCapture(func (event *tcell.EventKey) *tcell.EventKey {
+      if pp.focusedType == MENU {
+        c.HandleMenuKeyInput(event)
+
+      } else if event.Rune() == 'q' {
+        pp.SetCompType(MENU)
+        c.FocusMenu()
+        return nil
+      }
+
+      return event
+    })
+
+  return pp
+}
+
+func (pp *PlanPage) SetCompType(t ComponentType) {
+  pp.focusedType = t
+}
+
+func (pp *PlanPage) SetPlan(plan *Plan, statement string) {
+  pp.plan = plan
+
+  title := " " + strings.Join(strings.Fields(TrimStatement(statement)), " ")
+  if plan.analyzed {
+    title = fmt.Sprintf(" [yellow]ANALYZE[white] planning %.3fms, execution %.3fms |%s",
+      plan.planningTime, plan.executionTime, tview.Escape(title))
+  } else {
+    title = " [yellow]EXPLAIN[white] |" + tview.Escape(title)
+  }
+  pp.title.SetText(title)
+
+  root := pp.newNode(plan.root)
+  pp.tree.SetRoot(root).SetCurrentNode(root)
+  pp.ShowDetails(root)
+}
+
+func (pp *PlanPage) newNode(pn *PlanNode) *tview.TreeNode {
+  text := tview.Escape(pp.plan.Label(pn))
+
+  node := tview.NewTreeNode(text).
+    SetReference(pn).
+    SetSelectable(true).
+    SetExpanded(true)
+
+  switch {
+  case pn.hot == 1:
+    node.SetColor(tcell.ColorRed)
+  case pn.hot > 1:
+    node.SetColor(tcell.ColorOrange)
+  case pn.Misestimate() >= 10:
+    node.SetColor(tcell.ColorYellow)
+  }
+
+  for _, child := range pn.children {
+    node.AddChild(pp.newNode(child))
+  }
+
+  return node
+}
+
+func (pp *PlanPage) ShowDetails(node *tview.TreeNode) {
+  pn, ok := node.GetReference().(*PlanNode)
+  if !ok {
+    pp.details.SetText("")
+    return
+  }
+
+  pp.details.SetText(pn.Details())
+  pp.details.ScrollToBeginning()
+}
+
+func (pp *PlanPage) Layout() tview.Primitive {
+  return pp.layout
+}
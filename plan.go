@@ -0,0 +1,290 @@
+package main
+
+import (
+  "context"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "sort"
+  "strings"
+)
+
+// Node of an EXPLAIN (FORMAT JSON) plan
+type PlanNode struct {
+  fields   map[string]interface{}
+  children []*PlanNode
+
+  self float64 // time (or cost, without ANALYZE) spent in the node itself
+  hot  int     // 1 for the most expensive node, 2 for the next one... 0 if not hot
+}
+
+type Plan struct {
+  root     *PlanNode
+  analyzed bool
+
+  planningTime, executionTime float64
+  total float64 // sum of every node self
+}
+
+func NewPlanNode(fields map[string]interface{}) *PlanNode {
+  node := &PlanNode{fields: fields, children: []*PlanNode{}}
+
+  plans, _ := fields["Plans"].([]interface{})
+  for _, p := range plans {
+    child, ok := p.(map[string]interface{})
+    if ok {
+      node.children = append(node.children, NewPlanNode(child))
+    }
+  }
+
+  return node
+}
+
+func (pn *PlanNode) Num(key string) (float64, bool) {
+  v, ok := pn.fields[key].(float64)
+  return v, ok
+}
+
+func (pn *PlanNode) Str(key string) string {
+  v, _ := pn.fields[key].(string)
+  return v
+}
+
+// Time spent in the node and its children, counting every loop
+func (pn *PlanNode) TotalTime() float64 {
+  total, _ := pn.Num("Actual Total Time")
+  loops, ok := pn.Num("Actual Loops")
+  if !ok {
+    loops = 1
+  }
+  return total * loops
+}
+
+// How many times the estimated rows are off from the actual ones, 1 when the
+// estimate is right or the plan wasn't analyzed
+func (pn *PlanNode) Misestimate() float64 {
+  planned, ok1 := pn.Num("Plan Rows")
+  actual, ok2 := pn.Num("Actual Rows")
+
+  if !ok1 || !ok2 {
+    return 1
+  }
+
+  high, low := planned, actual
+  if actual > planned {
+    high, low = actual, planned
+  }
+
+  if low < 1 {
+    low = 1
+  }
+
+  return high / low
+}
+
+func (pn *PlanNode) Label(analyzed bool) string {
+  label := pn.Str("Node Type")
+
+  if name := pn.Str("Relation Name"); name != "" {
+    label += " on " + name
+    if alias := pn.Str("Alias"); alias != "" && alias != name {
+      label += " " + alias
+    }
+  }
+
+  if index := pn.Str("Index Name"); index != "" {
+    label += " using " + index
+  }
+
+  startup, _ := pn.Num("Startup Cost")
+  total, _ := pn.Num("Total Cost")
+  rows, _ := pn.Num("Plan Rows")
+  label += fmt.Sprintf("  cost=%.2f..%.2f rows=%.0f", startup, total, rows)
+
+  if analyzed {
+    actualStartup, _ := pn.Num("Actual Startup Time")
+    actualTotal, _ := pn.Num("Actual Total Time")
+    actualRows, _ := pn.Num("Actual Rows")
+    loops, _ := pn.Num("Actual Loops")
+
+    label += fmt.Sprintf("  actual=%.3f..%.3fms rows=%.0f loops=%.0f",
+      actualStartup, actualTotal, actualRows, loops)
+  }
+
+  if factor := pn.Misestimate(); factor >= 10 {
+    actualRows, _ := pn.Num("Actual Rows")
+    if actualRows > rows {
+      label += fmt.Sprintf("  [rows x%.0f under estimated]", factor)
+    } else {
+      label += fmt.Sprintf("  [rows x%.0f over estimated]", factor)
+    }
+  }
+
+  return label
+}
+
+// Every field of the node, but its children, one per line
+func (pn *PlanNode) Details() string {
+  keys := []string{}
+  for key := range pn.fields {
+    if key != "Plans" {
+      keys = append(keys, key)
+    }
+  }
+  sort.Strings(keys)
+
+  lines := []string{}
+  for _, key := range keys {
+    value := pn.fields[key]
+
+    switch v := value.(type) {
+    case []interface{}:
+      parts := []string{}
+      for _, item := range v {
+        parts = append(parts, fmt.Sprint(item))
+      }
+      value = strings.Join(parts, ", ")
+    case float64:
+      value = strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.3f", v), "0"), ".")
+    }
+
+    lines = append(lines, fmt.Sprintf("%s: %v", key, value))
+  }
+
+  return strings.Join(lines, "\n")
+}
+
+func (pn *PlanNode) Walk(fn func(*PlanNode)) {
+  fn(pn)
+  for _, child := range pn.children {
+    child.Walk(fn)
+  }
+}
+
+func ParsePlan(text string, analyzed bool) (*Plan, error) {
+  var output []map[string]interface{}
+
+  if err := json.Unmarshal([]byte(text), &output); err != nil {
+    return nil, err
+  }
+
+  if len(output) == 0 {
+    return nil, errors.New("Empty plan.")
+  }
+
+  root, ok := output[0]["Plan"].(map[string]interface{})
+  if !ok {
+    return nil, errors.New("Plan not found in the EXPLAIN output.")
+  }
+
+  plan := &Plan{root: NewPlanNode(root), analyzed: analyzed}
+  plan.planningTime, _ = output[0]["Planning Time"].(float64)
+  plan.executionTime, _ = output[0]["Execution Time"].(float64)
+
+  plan.rank()
+  return plan, nil
+}
+
+// Computes how much each node costs by itself and marks the most expensive ones
+func (p *Plan) rank() {
+  nodes := []*PlanNode{}
+
+  p.root.Walk(func (node *PlanNode) {
+    if p.analyzed {
+      node.self = node.TotalTime()
+      for _, child := range node.children {
+        node.self -= child.TotalTime()
+      }
+    } else {
+      node.self, _ = node.Num("Total Cost")
+      for _, child := range node.children {
+        cost, _ := child.Num("Total Cost")
+        node.self -= cost
+      }
+    }
+
+    if node.self < 0 {
+      node.self = 0
+    }
+
+    nodes = append(nodes, node)
+  })
+
+  p.total = 0
+  for _, node := range nodes {
+    p.total += node.self
+  }
+
+  sort.SliceStable(nodes, func (i, j int) bool {
+    return nodes[i].self > nodes[j].self
+  })
+
+  for i := 0; i < len(nodes) && i < 3; i++ {
+    if p.total > 0 && nodes[i].self >= p.total * 0.1 {
+      nodes[i].hot = i + 1
+    }
+  }
+}
+
+// Node label with the share of the plan it is responsible for
+func (p *Plan) Label(node *PlanNode) string {
+  return fmt.Sprintf("%s  self=%.1f%%", node.Label(p.analyzed), p.Share(node))
+}
+
+func (p *Plan) Share(node *PlanNode) float64 {
+  if p.total == 0 {
+    return 0
+  }
+  return node.self * 100 / p.total
+}
+
+// Runs EXPLAIN on the session. With ANALYZE the statement really runs, so it
+// is done inside a transaction (or savepoint) that is rolled back afterwards.
+// A SELECT can write too, through a data-modifying CTE or a function.
+func ExplainQuery(ctx context.Context, session *Session, statement string, analyze bool, args ...interface{}) (*Plan, error) {
+  options := "FORMAT JSON"
+  if analyze {
+    options += ", ANALYZE, BUFFERS"
+  }
+
+  query := "EXPLAIN (" + options + ") " + TrimStatement(statement)
+
+  undo := func () {}
+
+  if analyze {
+    background := context.Background()
+
+    if session.State() == TX_IDLE {
+      if err := session.Begin(background); err != nil {
+        return nil, err
+      }
+      undo = func () { session.Rollback(background) }
+    } else {
+      if err := session.Savepoint(background, "postdigress_explain"); err != nil {
+        return nil, err
+      }
+      undo = func () {
+        session.RollbackTo(background, "postdigress_explain")
+        session.Release(background, "postdigress_explain")
+      }
+    }
+  }
+
+  result := session.Query(ctx, query, args...)
+  undo()
+
+  if result.err != nil {
+    return nil, result.err
+  }
+
+  if len(result.values) == 0 || len(result.values[0]) == 0 {
+    return nil, errors.New("EXPLAIN gave no output.")
+  }
+
+  text := ""
+  for _, row := range result.values {
+    text += row[0]
+  }
+
+  return ParsePlan(text, analyze)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+  "context"
+  "errors"
+  "fmt"
+  "io"
+  "net"
+  "strings"
+  "sync"
+  "time"
+
+	"github.com/lib/pq"
+)
+
+type ConnState byte
+
+const (
+  CONN_UP ConnState = iota
+  CONN_LOST
+  CONN_RETRYING
+)
+
+const (
+  RECONNECT_MAX_DELAY = 30 * time.Second
+  RECONNECT_TIMEOUT   = 10 * time.Second
+  PING_TIMEOUT        = 5 * time.Second
+)
+
+// Tells if the error means the connection is gone, not that the statement failed
+func IsConnectionError(err error) bool {
+  if err == nil {
+    return false
+  }
+
+  if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+    errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+    return true
+  }
+
+  var netErr net.Error
+  if errors.As(err, &netErr) {
+    return true
+  }
+
+  var pqErr *pq.Error
+  if errors.As(err, &pqErr) {
+    // connection_exception, admin_shutdown, crash_shutdown, cannot_connect_now
+    return pqErr.Code.Class() == "08" ||
+      pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+  }
+
+  message := err.Error()
+  return strings.Contains(message, "broken pipe") ||
+    strings.Contains(message, "connection reset") ||
+    strings.Contains(message, "bad connection")
+}
+
+// Delay before the given reconnection attempt, doubling up to a limit
+func ReconnectDelay(attempt int) time.Duration {
+  delay := time.Duration(1 << uint(Min(Max(attempt - 1, 0), 5))) * time.Second
+
+  if delay > RECONNECT_MAX_DELAY {
+    return RECONNECT_MAX_DELAY
+  }
+  return delay
+}
+
+// Watches the connection of the session. Once it is lost, reconnect is
+// retried with a backoff until it works, and a keepalive ping can find out
+// about a dropped connection before the next query does.
+type ConnWatch struct {
+  mutex   sync.Mutex
+  state   ConnState
+  attempt int
+  retryAt time.Time
+  err     error
+
+  wake chan bool // retries right away, instead of waiting the delay
+
+  stopKeepalive chan bool
+
+  // Done once the watch is closed, which stops the retries
+  ctx    context.Context
+  cancel context.CancelFunc
+
+  reconnect   func(context.Context) error
+  ping        func(context.Context) error
+  onChange    func()
+  onReconnect func()
+}
+
+func NewConnWatch(reconnect, ping func(context.Context) error, onChange, onReconnect func()) *ConnWatch {
+  ctx, cancel := context.WithCancel(context.Background())
+
+  return &ConnWatch{
+    state: CONN_UP,
+    wake: make(chan bool, 1),
+    ctx: ctx,
+    cancel: cancel,
+    reconnect: reconnect,
+    ping: ping,
+    onChange: onChange,
+    onReconnect: onReconnect,
+  }
+}
+
+func (cw *ConnWatch) State() ConnState {
+  cw.mutex.Lock()
+  defer cw.mutex.Unlock()
+
+  return cw.state
+}
+
+// Text of the menu bar, empty while connected
+func (cw *ConnWatch) String() string {
+  cw.mutex.Lock()
+  defer cw.mutex.Unlock()
+
+  switch cw.state {
+  case CONN_LOST:
+    wait := time.Until(cw.retryAt).Round(time.Second)
+    return fmt.Sprintf("connection lost, retry %d in %s", cw.attempt, wait)
+  case CONN_RETRYING:
+    return fmt.Sprintf("reconnecting (retry %d)", cw.attempt)
+  }
+  return ""
+}
+
+func (cw *ConnWatch) Err() error {
+  cw.mutex.Lock()
+  defer cw.mutex.Unlock()
+
+  return cw.err
+}
+
+// Starts reconnecting, if that isn't already happening
+func (cw *ConnWatch) Lost(err error) {
+  cw.mutex.Lock()
+
+  if cw.state != CONN_UP || cw.Closed() {
+    cw.mutex.Unlock()
+    return
+  }
+
+  cw.state = CONN_LOST
+  cw.err = err
+  cw.attempt = 0
+  cw.mutex.Unlock()
+
+  go cw.retry()
+}
+
+// Skips the wait before the next attempt
+func (cw *ConnWatch) RetryNow() bool {
+  if cw.State() == CONN_UP {
+    return false
+  }
+
+  select {
+  case cw.wake <- true:
+  default:
+  }
+  return true
+}
+
+func (cw *ConnWatch) retry() {
+  for {
+    cw.mutex.Lock()
+    cw.attempt++
+    delay := ReconnectDelay(cw.attempt)
+    cw.retryAt = time.Now().Add(delay)
+    cw.state = CONN_LOST
+    cw.mutex.Unlock()
+
+    cw.onChange()
+
+    // The menu bar counts down to the attempt
+    timer := time.NewTimer(delay)
+    ticker := time.NewTicker(time.Second)
+
+  wait:
+    for {
+      select {
+      case <-cw.ctx.Done():
+        timer.Stop()
+        ticker.Stop()
+        return
+      case <-timer.C:
+        break wait
+      case <-cw.wake:
+        break wait
+      case <-ticker.C:
+        cw.onChange()
+      }
+    }
+
+    timer.Stop()
+    ticker.Stop()
+
+    cw.mutex.Lock()
+    cw.state = CONN_RETRYING
+    cw.mutex.Unlock()
+
+    cw.onChange()
+
+    ctx, cancel := context.WithTimeout(cw.ctx, RECONNECT_TIMEOUT)
+    err := cw.reconnect(ctx)
+    cancel()
+
+    if cw.Closed() {
+      return
+    }
+
+    cw.mutex.Lock()
+    cw.err = err
+    if err == nil {
+      cw.state = CONN_UP
+    }
+    cw.mutex.Unlock()
+
+    if err == nil {
+      cw.onChange()
+      cw.onReconnect()
+      return
+    }
+  }
+}
+
+// Pings the connection every interval, 0 stops it
+func (cw *ConnWatch) SetKeepalive(interval time.Duration) {
+  cw.mutex.Lock()
+  defer cw.mutex.Unlock()
+
+  if cw.stopKeepalive != nil {
+    close(cw.stopKeepalive)
+    cw.stopKeepalive = nil
+  }
+
+  if interval > 0 {
+    cw.stopKeepalive = make(chan bool)
+    go cw.keepAlive(interval, cw.stopKeepalive)
+  }
+}
+
+func (cw *ConnWatch) keepAlive(interval time.Duration, stop chan bool) {
+  ticker := time.NewTicker(interval)
+  defer ticker.Stop()
+
+  for {
+    select {
+    case <-stop:
+      return
+    case <-ticker.C:
+      if cw.State() != CONN_UP {
+        continue
+      }
+
+      ctx, cancel := context.WithTimeout(context.Background(), PING_TIMEOUT)
+      err := cw.ping(ctx)
+      cancel()
+
+      if IsConnectionError(err) || errors.Is(err, context.DeadlineExceeded) {
+        cw.Lost(err)
+      }
+    }
+  }
+}
+
+// Tells if the watch was closed, a reconnection it started must be dropped
+func (cw *ConnWatch) Closed() bool {
+  return cw.ctx.Err() != nil
+}
+
+func (cw *ConnWatch) Close() {
+  cw.cancel()
+  cw.SetKeepalive(0)
+}
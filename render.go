@@ -0,0 +1,172 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+  "github.com/gdamore/tcell"
+  "encoding/json"
+  "bytes"
+  "fmt"
+  "strings"
+)
+
+// Rows used by the column names and types on top of a result table
+const RESULT_HEADER_ROWS = 2
+
+// Shows a query result with the column types under the headers and numbered rows
+func TableSetResult(table *tview.Table, result QueryResult) {
+  TableSetHeader(table, result)
+
+  if len(result.values) == 0 {
+    c := Max(1, len(result.columns) / 2)
+    table.SetCell(3, c,
+      tview.NewTableCell(" No Data ").
+        SetTextColor(tcell.ColorBlue).
+        SetAlign(tview.AlignCenter))
+    return
+  }
+
+  TableAppendResult(table, result)
+}
+
+// Clears the table, leaving the column names and types of a result
+func TableSetHeader(table *tview.Table, result QueryResult) {
+  table.Clear()
+  table.SetFixed(RESULT_HEADER_ROWS, 1)
+
+  table.SetCell(0, 0,
+    tview.NewTableCell(" # ").
+      SetTextColor(tcell.ColorYellow).
+      SetAlign(tview.AlignCenter))
+
+  for j, name := range result.columns {
+    label := ""
+    if j < len(result.types) {
+      label = result.types[j].Label()
+    }
+
+    table.SetCell(0, j + 1,
+      tview.NewTableCell(" " + tview.Escape(name) + " ").
+        SetTextColor(tcell.ColorYellow).
+        SetAlign(tview.AlignCenter))
+
+    table.SetCell(1, j + 1,
+      tview.NewTableCell(" " + tview.Escape(label) + " ").
+        SetTextColor(tcell.ColorGray).
+        SetAlign(tview.AlignCenter))
+  }
+}
+
+// Appends the rows of a result after the ones already in the table
+func TableAppendResult(table *tview.Table, result QueryResult) {
+  start := table.GetRowCount()
+
+  for r, row := range result.values {
+    table.SetCell(start + r, 0,
+      tview.NewTableCell(fmt.Sprintf("%d", start + r - RESULT_HEADER_ROWS)).
+        SetTextColor(tcell.ColorYellow).
+        SetAlign(tview.AlignCenter))
+
+    for c, value := range row {
+      column := ColumnInfo{}
+      if c < len(result.types) {
+        column = result.types[c]
+      }
+
+      table.SetCell(start + r, c + 1, ResultCell(value, result.IsNull(r, c), column))
+    }
+  }
+}
+
+func ResultCell(value string, null bool, column ColumnInfo) *tview.TableCell {
+  if null {
+    return tview.NewTableCell(" NULL ").
+      SetTextColor(tcell.ColorGray).
+      SetAlign(tview.AlignLeft)
+  }
+
+  align := tview.AlignLeft
+  if column.IsNumeric() {
+    align = tview.AlignRight
+  }
+
+  return tview.NewTableCell(" " + tview.Escape(RenderValue(value, column.dbType)) + " ").
+    SetTextColor(tcell.ColorWhite).
+    SetAlign(align)
+}
+
+// Text of a value as shown in the table, arrays and json get some spacing
+func RenderValue(value string, dbType string) string {
+  switch {
+  case dbType == "JSON" || dbType == "JSONB":
+    return PrettyJSON(value)
+  case strings.HasPrefix(dbType, "_"):
+    return PrettyArray(value)
+  }
+  return value
+}
+
+// Puts a space after the commas and colons of a json, keeping it in one line
+func PrettyJSON(value string) string {
+  var compact bytes.Buffer
+
+  if json.Compact(&compact, []byte(value)) != nil {
+    return value
+  }
+
+  var result strings.Builder
+  inString, escaped := false, false
+
+  for _, ch := range compact.String() {
+    result.WriteRune(ch)
+
+    if inString {
+      if escaped {
+        escaped = false
+      } else if ch == '\\' {
+        escaped = true
+      } else if ch == '"' {
+        inString = false
+      }
+      continue
+    }
+
+    switch ch {
+    case '"':
+      inString = true
+    case ',', ':':
+      result.WriteRune(' ')
+    }
+  }
+
+  return result.String()
+}
+
+// Puts a space after the element separators of an array literal like {1,2,3}
+func PrettyArray(value string) string {
+  var result strings.Builder
+  inString, escaped := false, false
+
+  for _, ch := range value {
+    result.WriteRune(ch)
+
+    if inString {
+      if escaped {
+        escaped = false
+      } else if ch == '\\' {
+        escaped = true
+      } else if ch == '"' {
+        inString = false
+      }
+      continue
+    }
+
+    switch ch {
+    case '"':
+      inString = true
+    case ',':
+      result.WriteRune(' ')
+    }
+  }
+
+  return result.String()
+}
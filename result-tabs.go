@@ -0,0 +1,199 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+  "fmt"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// Outcome of one statement of an executed script
+type StatementResult struct {
+  query  string
+  args   []interface{}
+  result QueryResult
+  cursor *ResultCursor
+
+  notices []Notice
+
+  previous *StatementResult // last result of the same query, to compare with
+
+  duration  time.Duration
+  cancelled bool
+  exec      bool
+}
+
+func (sr *StatementResult) Failed() bool {
+  return sr.cancelled || sr.result.err != nil
+}
+
+// Tells if rows of the result were never fetched
+func (sr *StatementResult) Partial() bool {
+  return sr.cursor != nil && !sr.cursor.Exhausted()
+}
+
+func (sr *StatementResult) Title() string {
+  tokens, input := Tokenize(sr.query)
+
+  if len(tokens) == 0 {
+    return "?"
+  }
+
+  return strings.ToUpper(tokens[0].Text(input))
+}
+
+func (sr *StatementResult) Summary() string {
+  summary := sr.summary()
+
+  if len(sr.notices) > 0 {
+    summary += fmt.Sprintf(" (%d message(s))", len(sr.notices))
+  }
+
+  return summary
+}
+
+func (sr *StatementResult) summary() string {
+  if sr.cancelled {
+    return "Query cancelled after " + FormatElapsed(sr.duration)
+  }
+
+  if sr.result.err != nil {
+    if message := TimeoutMessage(sr.result.err); message != "" {
+      return message
+    }
+    return sr.result.err.Error()
+  }
+
+  if sr.cursor != nil {
+    return "Finished in " + sr.duration.String() + ", " + sr.cursor.Status()
+  }
+
+  return sr.Tag() + ", finished in " + sr.duration.String()
+}
+
+// Rows affected by an exec, or given back by a query
+func (sr *StatementResult) Rows() int64 {
+  if !sr.exec {
+    return int64(len(sr.result.values))
+  }
+
+  if sr.result.affected < 0 {
+    return 0
+  }
+  return sr.result.affected
+}
+
+func (sr *StatementResult) Tag() string {
+  if sr.exec {
+    return CommandTag(sr.query, sr.result.affected)
+  }
+
+  return CommandTag(sr.query, int64(len(sr.result.values)))
+}
+
+// Bar with one tab per statement result, plus a Messages tab when the
+// statements got any notice
+type ResultTabs struct {
+  tv *tview.TextView
+
+  results []*StatementResult
+  current int
+  notices int
+}
+
+func NewResultTabs() *ResultTabs {
+  rt := &ResultTabs{
+    tv: tview.NewTextView(),
+    results: []*StatementResult{},
+    current: -1,
+  }
+
+  rt.tv.
+    SetDynamicColors(true).
+    SetRegions(true).
+    SetWrap(false)
+
+  return rt
+}
+
+func (rt *ResultTabs) SetResults(results []*StatementResult) {
+  rt.results = results
+  rt.current = len(results) - 1
+
+  rt.notices = 0
+  for _, sr := range results {
+    rt.notices += len(sr.notices)
+  }
+
+  rt.Render()
+}
+
+// Notices that came after the result was shown, while fetching its pages
+func (rt *ResultTabs) AddNotices(sr *StatementResult, notices []Notice) {
+  sr.notices = append(sr.notices, notices...)
+
+  for _, result := range rt.results {
+    if result == sr {
+      rt.notices += len(notices)
+      rt.Render()
+      return
+    }
+  }
+}
+
+func (rt *ResultTabs) Current() *StatementResult {
+  if rt.current >= 0 && rt.current < len(rt.results) {
+    return rt.results[rt.current]
+  }
+  return nil
+}
+
+// The Messages tab comes after the last result
+func (rt *ResultTabs) OnMessages() bool {
+  return rt.notices > 0 && rt.current == len(rt.results)
+}
+
+func (rt *ResultTabs) Select(index int) bool {
+  last := len(rt.results) - 1
+  if rt.notices > 0 {
+    last++
+  }
+
+  if index < 0 || index > last || index == rt.current {
+    return false
+  }
+
+  rt.current = index
+  rt.Render()
+  return true
+}
+
+func (rt *ResultTabs) Next() bool {
+  return rt.Select(rt.current + 1)
+}
+
+func (rt *ResultTabs) Prev() bool {
+  return rt.Select(rt.current - 1)
+}
+
+func (rt *ResultTabs) Render() {
+  text := ""
+
+  for i, sr := range rt.results {
+    color := "white"
+    if sr.Failed() {
+      color = "red"
+    }
+
+    text += fmt.Sprintf(`["%d"][%s] %d %s [white][""] `, i, color, i + 1, sr.Title())
+  }
+
+  if rt.notices > 0 {
+    text += fmt.Sprintf(`["%d"][yellow] Messages (%d) [white][""] `, len(rt.results), rt.notices)
+  }
+
+  rt.tv.SetText(text)
+  rt.tv.Highlight(strconv.Itoa(rt.current))
+  rt.tv.ScrollToHighlight()
+}
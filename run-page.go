@@ -8,6 +8,7 @@ import (
 
   "strconv"
   "strings"
+  "context"
 	"database/sql"
 )
 
@@ -25,15 +26,43 @@ type RunPage struct {
 
   table *tview.Table
   tableMode TableMode
+  tabs *ResultTabs
 
   focusedType ComponentType
 
   focused  *tview.TextView
 	modeName *tview.TextView
+  txState  *tview.TextView
   layout   *tview.Grid
 
 	status  *Status
   command *Command
+
+  cancelQuery  context.CancelFunc
+  cancelExport context.CancelFunc // table export, it runs outside of the session
+  cancelFetch  context.CancelFunc
+
+  fetching bool
+  pageSize int
+  maxRows  int
+
+  stopOnError bool
+  guard       bool // confirm destructive statements, always on for protected connections
+
+  params map[string]string // values of the bind parameters, by placeholder
+
+  // Last result of each query, by its text, and the key columns chosen to compare them
+  lastResults map[string]*StatementResult
+  lastQueries []string
+  compareKeys map[string][]string
+  compare     bool
+  diff        *ResultDiff
+
+  // Statements that failed because the connection was lost, offered again
+  // once it is back, and if the transaction of the old session was lost
+  retryScript     string
+  retryStatements []string
+  lostTx          bool
 }
 
 func NewRunPage(c *Context) *RunPage {
@@ -46,6 +75,16 @@ func NewRunPage(c *Context) *RunPage {
 
   rp.tableMode = NONE
 
+  rp.pageSize = c.config.GetPageSize()
+  rp.maxRows = c.config.GetMaxRows()
+  rp.stopOnError = true
+  rp.guard = true
+  rp.params = map[string]string{}
+  rp.lastResults = map[string]*StatementResult{}
+  rp.compareKeys = map[string][]string{}
+
+  rp.tabs = NewResultTabs()
+
 	rp.table = tview.NewTable().
 		SetBorders(false).
 		SetSeparator(tview.Borders.Vertical)
@@ -53,7 +92,7 @@ func NewRunPage(c *Context) *RunPage {
 	rp.table.Select(0, 0).SetFixed(1, 1).
     SetDoneFunc(func (key tcell.Key) {
       if key == tcell.KeyEscape {
-        c.Finish()
+        c.Quit()
       }
     }).
     SetInputCapture(func (event *tcell.EventKey) *tcell.EventKey {
@@ -65,10 +104,41 @@ func NewRunPage(c *Context) *RunPage {
           rp.tableMode & 2 != 0)
 
         rp.SetModeName()
+      } else if event.Rune() == ']' {
+        if rp.tabs.Next() {
+          rp.ShowCurrentResult()
+        }
+        return nil
+      } else if event.Rune() == '[' {
+        if rp.tabs.Prev() {
+          rp.ShowCurrentResult()
+        }
+        return nil
+      } else if event.Rune() == 'c' {
+        if rp.compare {
+          rp.status.SetText(rp.Compare(c, "off"))
+        } else {
+          rp.status.SetText(rp.Compare(c))
+        }
+        return nil
+      }
+
+      switch event.Key() {
+      case tcell.KeyDown, tcell.KeyPgDn, tcell.KeyEnd, tcell.KeyCtrlF:
+        rp.FetchMore(c)
+      case tcell.KeyRune:
+        if event.Rune() == 'j' || event.Rune() == 'G' {
+          rp.FetchMore(c)
+        }
       }
       return event
     })
 
+  rp.table.SetSelectionChangedFunc(func (row, column int) {
+    rp.FetchMore(c)
+    rp.ShowDiffCell(row, column)
+  })
+
   TableSetData(rp.table, []string{" "}, [][]string{}, false)
 
 	rp.modeName = tview.NewTextView().
@@ -79,64 +149,85 @@ func NewRunPage(c *Context) *RunPage {
 		SetDynamicColors(true).
 		SetWrap(false)
 
+	rp.txState = tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(false)
+
+  rp.UpdateTxState(c)
+
   rp.SetCompType(MENU)
 
   rp.editor.SetModeChangeCb(func (m Mode) {
     rp.SetModeName()
   })
 
+  rp.editor.SetQueueUpdateCb(c.Enqueue)
   rp.editor.SetExecuteCb(func (query string) {
-    if c.loading.waiting {
-      return
-    }
-
-    c.loading.SetTextView(rp.status.tv)
-    go c.loading.Init(c.app)
-
-    go func () {
-      startTime := time.Now()
-
-      var queryResult QueryResult
-
-      if len(query) > 0 {
-        queryResult = GetQueryResult(c.db, query)
-      } else {
-        c.Enqueue(func () {
-          rp.status.SetText("Nothing to be done.")
-        })
-        return
-      }
-
-      duration := time.Now().Sub(startTime)
-
-      c.loading.Close()
+    rp.Execute(c, query)
+  })
 
-      if queryResult.err != nil {
-        c.Enqueue(func () {
-          rp.status.SetText(queryResult.err.Error())
-          TableSetData(rp.table, []string{}, [][]string{}, false)
-        })
-      } else if len(queryResult.columns) == 0 {
-        c.Enqueue(func () {
-          rp.status.SetText("Finished in " + duration.String())
-          TableSetData(rp.table, []string{}, [][]string{}, false)
-        })
-      } else {
-        c.Enqueue(func () {
-          rp.status.SetText("Finished in " + duration.String())
+  rp.editor.SetExecuteJobCb(func (query string) {
+    rp.ExecuteJob(c, query)
+  })
 
-          TableSetData(rp.table, queryResult.columns, queryResult.values, true)
-        })
-      }
-    }()
+  rp.editor.SetExplainCb(func (query string, analyze bool) {
+    rp.Explain(c, query, analyze)
   })
 
   rp.command = NewCommand()
   rp.command.Register("yank", rp.Yank)
   rp.command.Register("yank-line", rp.YankLine)
   rp.command.Register("import", rp.Import)
+  rp.command.Register("import-csv",
+    func(path, table string) string { return rp.ImportCsv(c, path, table) })
   rp.command.Register("export", rp.Export)
+  rp.command.Register("export-result", rp.ExportResult)
+  rp.command.Register("export-table",
+    func(table, path string, format ...string) string {
+      if len(format) == 0 {
+        format = []string{"csv"}
+      }
+      return rp.ExportTable(c, table, path, format[0])
+    })
   rp.command.Register("enable", rp.Enable)
+  rp.command.Register("set",
+    func(item string, value int) string { return rp.Set(c, item, value) })
+  rp.command.Register("compare",
+    func(columns ...string) string { return rp.Compare(c, columns...) })
+  rp.command.Register("explain",
+    func() string { return rp.ExplainResult(c, false) })
+  rp.command.Register("explain-analyze",
+    func() string { return rp.ExplainResult(c, true) })
+
+  rp.command.Register("goto-table",
+    func(table ...string) string { return rp.GotoTable(c, table...) })
+  rp.command.Register("refresh-keywords",
+    func() string { return rp.RefreshKeywords(c) })
+  rp.command.Register("reconnect",
+    func() string { return rp.Reconnect(c) })
+  rp.command.Register("begin",
+    func() string { return rp.Transaction(c, "begin", "") })
+  rp.command.Register("commit",
+    func() string { return rp.Transaction(c, "commit", "") })
+  rp.command.Register("rollback",
+    func() string { return rp.Transaction(c, "rollback", "") })
+  rp.command.Register("savepoint",
+    func(name string) string { return rp.Transaction(c, "savepoint", name) })
+  rp.command.Register("rollback-to",
+    func(name string) string { return rp.Transaction(c, "rollback-to", name) })
+  rp.command.Register("release",
+    func(name string) string { return rp.Transaction(c, "release", name) })
+  rp.command.Register("isolation",
+    func(level string) string { return rp.Transaction(c, "isolation", level) })
+
+  rp.command.Register("listen",
+    func(channel string) string { return rp.Listen(c, channel, true) })
+  rp.command.Register("unlisten",
+    func(channel string) string { return rp.Listen(c, channel, false) })
+  rp.command.Register("notify",
+    func(channel string, payload ...string) string {
+      return rp.Notify(c, channel, strings.Join(payload, " "))
+    })
 
   rp.command.Register("table-get", rp.TableGet)
   rp.command.Register("select-for", rp.YankSelectFor)
@@ -159,10 +250,12 @@ func NewRunPage(c *Context) *RunPage {
     } else {
       rp.status.SetText(returned)
     }
-    // Change Focus
+    // Change Focus, unless the command opened a dialog
 
-    rp.SetCompType(EDITOR)
-    c.SetFocus(rp.editor.tv)
+    if c.app.GetFocus() == rp.status.tv {
+      rp.SetCompType(EDITOR)
+      c.SetFocus(rp.editor.tv)
+    }
   })
 
   rp.status.SetCancelCb(func() {
@@ -173,17 +266,53 @@ func NewRunPage(c *Context) *RunPage {
 
 	rp.layout = tview.NewGrid().
 		SetBorders(true).
-		SetRows(1, -2, -3, 1).
-		SetColumns(8, 8, -1).
-		AddItem(c.menuBar,    0, 0, 1, 3, 0, 0, true).
-		AddItem(rp.editor.tv, 1, 0, 1, 3, 0, 0, false).
-		AddItem(rp.table,     2, 0, 1, 3, 0, 0, false).
-		AddItem(rp.focused,   3, 0, 1, 1, 0, 0, false).
-		AddItem(rp.modeName,  3, 1, 1, 1, 0, 0, false).
-		AddItem(rp.status.tv, 3, 2, 1, 1, 0, 0, false)
+		SetRows(1, -2, 1, -3, 1).
+		SetColumns(8, 8, 16, -1).
+		AddItem(c.menuBar,    0, 0, 1, 4, 0, 0, true).
+		AddItem(rp.editor.tv, 1, 0, 1, 4, 0, 0, false).
+		AddItem(rp.tabs.tv,   2, 0, 1, 4, 0, 0, false).
+		AddItem(rp.table,     3, 0, 1, 4, 0, 0, false).
+		AddItem(rp.focused,   4, 0, 1, 1, 0, 0, false).
+		AddItem(rp.modeName,  4, 1, 1, 1, 0, 0, false).
+		AddItem(rp.txState,   4, 2, 1, 1, 0, 0, false).
+		AddItem(rp.status.tv, 4, 3, 1, 1, 0, 0, false)
 
   rp.layout.
     SetInputCapture(func (event *tcell.EventKey) *tcell.EventKey {
+      if event.Key() == tcell.KeyCtrlQ && (rp.cancelQuery != nil || rp.cancelFetch != nil) {
+        rp.CancelQuery()
+        return nil
+      }
+
+      if event.Key() == tcell.KeyCtrlQ && rp.cancelExport != nil {
+        rp.cancelExport()
+        return nil
+      }
+
+      if rp.focusedType != COMMAND {
+        command := ""
+
+        switch event.Key() {
+        case tcell.KeyF5:
+          command = "begin"
+        case tcell.KeyF6:
+          command = "commit"
+        case tcell.KeyF7:
+          command = "rollback"
+        case tcell.KeyF8:
+          command = "savepoint"
+        case tcell.KeyF9:
+          command = "rollback-to"
+        case tcell.KeyF10:
+          command = "isolation"
+        }
+
+        if command != "" {
+          rp.status.SetText(rp.Transaction(c, command, ""))
+          return nil
+        }
+      }
+
       if rp.focusedType == EDITOR && rp.editor.mode == NORMAL {
         if event.Rune() == 'q' {
           rp.SetCompType(MENU)
@@ -268,6 +397,835 @@ func (rp *RunPage) SetModeName() {
   }
 }
 
+// Cancels the running query, the session asks the server to cancel it and stays open
+func (rp *RunPage) CancelQuery() {
+  if rp.cancelQuery != nil {
+    rp.cancelQuery()
+  }
+
+  if rp.cancelFetch != nil {
+    rp.cancelFetch()
+  }
+}
+
+// Runs each statement of the script in order, every one of them gets a result tab
+func (rp *RunPage) Execute(c *Context, script string) {
+  if c.loading.waiting {
+    return
+  }
+
+  rp.PrepareScript(c, script, func (statements []string) {
+    rp.RunScript(c, script, statements)
+  })
+}
+
+// Runs the script as a background job, on a session of its own
+func (rp *RunPage) ExecuteJob(c *Context, script string) {
+  rp.PrepareScript(c, script, func (statements []string) {
+    job := c.jobs.Start(c, script, statements, rp.ScriptArgs(statements))
+    rp.status.SetText(fmt.Sprintf("Job %d started.", job.id))
+  })
+}
+
+// Splits the script, checks it can run and asks for its parameters before
+// calling run
+func (rp *RunPage) PrepareScript(c *Context, script string, run func([]string)) {
+  statements := SplitStatements(script)
+
+  if len(statements) == 0 {
+    rp.status.SetText("Nothing to be done.")
+    return
+  }
+
+  if c.info.readOnly {
+    for _, statement := range statements {
+      if !IsReadOnlyStatement(statement) {
+        rp.status.SetText("Read only connection, refusing to run: " + TrimStatement(statement))
+        return
+      }
+    }
+  }
+
+  rp.Guard(c, statements, func () {
+    rp.AskParams(c, ScriptParamKeys(statements), func () {
+      run(statements)
+    })
+  })
+}
+
+// Asks for a confirmation before running destructive statements, when the
+// guard is on or the connection is protected
+func (rp *RunPage) Guard(c *Context, statements []string, run func()) {
+  hazards := ScriptHazards(statements)
+
+  if len(hazards) == 0 || !(rp.guard || c.info.protected) {
+    run()
+    return
+  }
+
+  text := "This script will run:\n"
+  if c.info.protected {
+    text = "Protected connection! " + text
+  }
+
+  for _, hazard := range hazards {
+    text += "\n" + hazard.String()
+  }
+
+  c.Confirm(text + "\n\nRun it anyway?", []string{"Cancel", "Run"}, func (label string) {
+    if label != "Run" {
+      rp.status.SetText("Execution cancelled.")
+      return
+    }
+    run()
+  })
+}
+
+// Prompts for the value of each placeholder, the last value given to a
+// placeholder is kept and used when the answer is empty
+func (rp *RunPage) AskParams(c *Context, keys []string, done func()) {
+  if len(keys) == 0 {
+    done()
+    return
+  }
+
+  key := keys[0]
+  question := key + " = "
+
+  value, known := rp.params[key]
+  if known {
+    question = fmt.Sprintf("%s [%s] = ", key, value)
+  }
+
+  rp.Ask(c, question, func (answer string, ok bool) {
+    if !ok {
+      rp.status.SetText("Execution cancelled.")
+      return
+    }
+
+    if answer != "" || !known {
+      rp.params[key] = answer
+    }
+
+    rp.AskParams(c, keys[1:], done)
+  })
+}
+
+func (rp *RunPage) Ask(c *Context, question string, done func(string, bool)) {
+  rp.SetCompType(COMMAND)
+  c.SetFocus(rp.status.tv)
+
+  rp.status.Ask(question, func (answer string, ok bool) {
+    rp.status.SetMode(Show)
+    rp.SetCompType(EDITOR)
+    c.SetFocus(rp.editor.tv)
+
+    done(answer, ok)
+  })
+}
+
+// Bind values of the statement placeholders, NULL is sent as a real null
+func (rp *RunPage) ParamArgs(keys []string) []interface{} {
+  args := make([]interface{}, len(keys))
+
+  for i, key := range keys {
+    value := rp.params[key]
+
+    if strings.ToUpper(value) == "NULL" {
+      args[i] = nil
+    } else {
+      args[i] = value
+    }
+  }
+
+  return args
+}
+
+// Bind values of each statement of the script
+func (rp *RunPage) ScriptArgs(statements []string) [][]interface{} {
+  args := [][]interface{}{}
+
+  for _, statement := range statements {
+    _, keys := BindParams(statement)
+    args = append(args, rp.ParamArgs(keys))
+  }
+
+  return args
+}
+
+func (rp *RunPage) RunScript(c *Context, script string, statements []string) {
+  if c.loading.waiting {
+    return
+  }
+
+  if c.watch != nil && c.watch.State() != CONN_UP {
+    rp.status.SetText("The connection was lost, waiting to reconnect (:reconnect retries now).")
+    return
+  }
+
+  stopOnError := ScriptStopOnError(script, rp.stopOnError)
+  args := rp.ScriptArgs(statements)
+
+  ctx, cancel := context.WithCancel(context.Background())
+  rp.cancelQuery = cancel
+  session := c.session
+
+  c.loading.SetTextView(rp.status.tv)
+  go c.loading.Init(c.app)
+
+  go func () {
+    defer cancel()
+
+    results := []*StatementResult{}
+
+    // The session closes the cursor of a statement before the next one runs,
+    // so only the last result keeps its cursor open
+    for i, statement := range statements {
+      sr := rp.RunStatement(ctx, session, statement, args[i])
+      results = append(results, sr)
+      c.history.Add(NewHistoryEntry(c.info, sr))
+
+      if sr.cancelled || (sr.result.err != nil && stopOnError) {
+        break
+      }
+    }
+
+    c.loading.Close()
+
+    skipped := len(statements) - len(results)
+
+    c.Enqueue(func () {
+      rp.cancelQuery = nil
+      rp.Remember(c, results)
+      rp.tabs.SetResults(results)
+      rp.ShowCurrentResult()
+      rp.UpdateTxState(c)
+
+      for i, sr := range results {
+        if c.CheckConnection(sr.result.err) {
+          rp.retryScript = script
+          rp.retryStatements = statements[i:]
+          rp.status.SetText("The connection was lost, reconnecting. " + sr.Summary())
+          return
+        }
+      }
+
+      if skipped > 0 {
+        summary := rp.tabs.Current().Summary()
+        rp.status.SetText(fmt.Sprintf("%d statement(s) skipped. %s", skipped, summary))
+      }
+    })
+  }()
+}
+
+func (rp *RunPage) RunStatement(ctx context.Context, session *Session, statement string, args []interface{}) *StatementResult {
+  bound, _ := BindParams(statement)
+  sr := &StatementResult{query: statement, args: args}
+
+  startTime := time.Now()
+
+  // Anything received before belongs to another statement
+  session.TakeNotices()
+
+  if IsCursorQuery(statement) {
+    sr.cursor, sr.result = session.OpenCursor(ctx, bound, rp.pageSize, rp.maxRows, sr.args...)
+  } else if ReturnsRows(statement) {
+    sr.result = session.Query(ctx, bound, sr.args...)
+  } else {
+    sr.exec = true
+    sr.result = session.Exec(ctx, bound, sr.args...)
+  }
+
+  sr.duration = time.Now().Sub(startTime)
+  sr.cancelled = ctx.Err() == context.Canceled
+  sr.notices = session.TakeNotices()
+
+  return sr
+}
+
+// Shows the plan of the first statement of the script in the Plan page
+func (rp *RunPage) Explain(c *Context, script string, analyze bool) {
+  if c.loading.waiting {
+    return
+  }
+
+  statements := SplitStatements(script)
+
+  if len(statements) == 0 {
+    rp.status.SetText("Nothing to be explained.")
+    return
+  }
+
+  _, keys := BindParams(statements[0])
+
+  if analyze && c.info.readOnly && !IsReadOnlyStatement(statements[0]) {
+    rp.status.SetText("Read only connection, refusing to analyze a write.")
+    return
+  }
+
+  explain := func () {
+    rp.AskParams(c, keys, func () {
+      rp.RunExplain(c, statements[0], rp.ParamArgs(keys), analyze)
+    })
+  }
+
+  // ANALYZE really runs the statement
+  if analyze {
+    rp.Guard(c, statements[:1], explain)
+  } else {
+    explain()
+  }
+}
+
+// Explains the statement of the current result tab, with the same values
+func (rp *RunPage) ExplainResult(c *Context, analyze bool) string {
+  sr := rp.tabs.Current()
+  if sr == nil {
+    return "No statement to be explained."
+  }
+
+  if c.loading.waiting {
+    return "Wait for the running query."
+  }
+
+  if analyze && c.info.readOnly && !IsReadOnlyStatement(sr.query) {
+    return "Read only connection, refusing to analyze a write."
+  }
+
+  explain := func () {
+    rp.RunExplain(c, sr.query, sr.args, analyze)
+  }
+
+  if analyze {
+    rp.Guard(c, []string{sr.query}, explain)
+  } else {
+    explain()
+  }
+  return "Explaining..."
+}
+
+func (rp *RunPage) RunExplain(c *Context, statement string, args []interface{}, analyze bool) {
+  if c.loading.waiting {
+    return
+  }
+
+  bound, _ := BindParams(statement)
+
+  ctx, cancel := context.WithCancel(context.Background())
+  rp.cancelQuery = cancel
+
+  c.loading.SetTextView(rp.status.tv)
+  go c.loading.Init(c.app)
+
+  go func () {
+    defer cancel()
+
+    plan, err := ExplainQuery(ctx, c.session, bound, analyze, args...)
+
+    c.loading.Close()
+
+    c.Enqueue(func () {
+      rp.cancelQuery = nil
+      rp.UpdateTxState(c)
+
+      if message := TimeoutMessage(err); message != "" {
+        rp.status.SetText(message)
+        return
+      }
+
+      if err != nil {
+        rp.status.SetText(err.Error())
+        return
+      }
+
+      rp.status.SetText("Plan ready.")
+      c.planPage.SetPlan(plan, statement)
+      c.ShowPlan()
+    })
+  }()
+}
+
+// Puts a query back into the editor, running it again when asked
+func (rp *RunPage) Recall(c *Context, query string, run bool) {
+  c.menuBar.Highlight("0")
+  c.selectedMenu = RUN_MENU
+
+  rp.editor.SetText(TextFromString(query))
+  rp.SetCompType(EDITOR)
+  c.SetFocus(rp.editor.tv)
+
+  if run {
+    rp.Execute(c, query)
+  }
+}
+
+// Shows the results of a finished job in the table
+func (rp *RunPage) ShowJob(c *Context, job *Job) {
+  c.menuBar.Highlight("0")
+  c.selectedMenu = RUN_MENU
+
+  rp.Remember(c, job.results)
+  rp.tabs.SetResults(job.results)
+  rp.ShowCurrentResult()
+
+  rp.SetCompType(TABLE)
+  c.SetFocus(rp.table)
+}
+
+func (rp *RunPage) ShowCurrentResult() {
+  if rp.tabs.OnMessages() {
+    rp.status.SetText(fmt.Sprintf("%d message(s).", rp.tabs.notices))
+    TableSetMessages(rp.table, rp.tabs.results)
+    return
+  }
+
+  sr := rp.tabs.Current()
+
+  if sr == nil {
+    return
+  }
+
+  rp.status.SetText(sr.Summary())
+  rp.diff = nil
+
+  if sr.Failed() || len(sr.result.columns) == 0 {
+    TableSetData(rp.table, []string{}, [][]string{}, false)
+  } else if rp.compare && sr.previous != nil {
+    diff := DiffResults(sr.previous.result, sr.result, rp.DiffKey(sr))
+    diff.partial = sr.Partial() || sr.previous.Partial()
+    rp.diff = &diff
+
+    TableSetDiff(rp.table, sr.result, diff)
+    rp.status.SetText(sr.Summary() + ". " + diff.Summary())
+  } else {
+    TableSetResult(rp.table, sr.result)
+  }
+}
+
+// Keeps the results that return rows, each one gets the previous result of its query
+func (rp *RunPage) Remember(c *Context, results []*StatementResult) {
+  for _, sr := range results {
+    if sr.Failed() || sr.exec || len(sr.result.columns) == 0 {
+      continue
+    }
+
+    key := DiffQueryKey(sr.query)
+
+    // The same result shown again, like a job opened twice, keeps its previous
+    if previous, ok := rp.lastResults[key]; ok && previous == sr {
+      continue
+    }
+
+    if previous, ok := rp.lastResults[key]; ok {
+      previous.previous = nil
+      sr.previous = previous
+
+      if _, ok := rp.compareKeys[key]; !ok {
+        go rp.LookupDiffKey(c, key, sr)
+      }
+    } else {
+      rp.lastQueries = append(rp.lastQueries, key)
+    }
+
+    rp.lastResults[key] = sr
+  }
+
+  // Only the results of the latest queries are kept
+  for len(rp.lastQueries) > MAX_REMEMBERED_QUERIES {
+    delete(rp.lastResults, rp.lastQueries[0])
+    delete(rp.compareKeys, rp.lastQueries[0])
+    rp.lastQueries = rp.lastQueries[1:]
+  }
+}
+
+// Takes the primary key of the table of the query as the key to compare its
+// results, the lookup runs outside of the UI
+func (rp *RunPage) LookupDiffKey(c *Context, key string, sr *StatementResult) {
+  pk := PrimaryKeyColumns(c.db, SourceTable(sr.query))
+
+  c.Enqueue(func () {
+    if _, ok := rp.compareKeys[key]; ok {
+      return
+    }
+
+    rp.compareKeys[key] = DefaultDiffKey(sr.result, pk)
+
+    if rp.compare && sr == rp.tabs.Current() {
+      rp.ShowCurrentResult()
+    }
+  })
+}
+
+func (rp *RunPage) DiffKey(sr *StatementResult) []string {
+  if key, ok := rp.compareKeys[DiffQueryKey(sr.query)]; ok {
+    return key
+  }
+  return DefaultDiffKey(sr.result, []string{})
+}
+
+// Turns the compare mode on, by the given key columns (the primary key of
+// the table of the query by default), or off
+func (rp *RunPage) Compare(c *Context, columns ...string) string {
+  if len(columns) == 1 && columns[0] == "off" {
+    rp.compare = false
+    rp.ShowCurrentResult()
+    return "compare disabled."
+  }
+
+  rp.compare = true
+  sr := rp.tabs.Current()
+
+  if sr == nil || rp.tabs.OnMessages() || sr.Failed() || len(sr.result.columns) == 0 {
+    return "compare enabled."
+  }
+
+  key := DiffQueryKey(sr.query)
+
+  if len(columns) > 0 {
+    if len(ColumnIndexes(sr.result.columns, columns)) < len(columns) {
+      return "unknown column in " + strings.Join(columns, ", ") + "."
+    }
+    rp.compareKeys[key] = columns
+  }
+
+  rp.ShowCurrentResult()
+
+  if rp.diff == nil {
+    return "compare enabled, no previous run of this query."
+  }
+
+  return sr.Summary() + ". " + rp.diff.Summary()
+}
+
+// Shows the previous value of a changed cell
+func (rp *RunPage) ShowDiffCell(row, column int) {
+  r, c := row - RESULT_HEADER_ROWS, column - 1
+
+  if rp.diff == nil || r < 0 || r >= len(rp.diff.rows) || c < 0 {
+    return
+  }
+
+  dr := rp.diff.rows[r]
+
+  if c < len(dr.changed) && dr.changed[c] && dr.state == ROW_CHANGED {
+    rp.status.SetText("was: " + dr.before[c])
+  }
+}
+
+// Runs a transaction command in the session of the page
+func (rp *RunPage) Transaction(c *Context, command, arg string) string {
+  if c.session == nil {
+    return "Not connected."
+  }
+
+  if rp.cancelQuery != nil || c.loading.waiting {
+    return "A query is running."
+  }
+
+  if rp.fetching {
+    return "Wait for the rows being fetched."
+  }
+
+  session := c.session
+
+  var run func(context.Context) error
+  msg := ""
+
+  switch command {
+  case "begin":
+    run = session.Begin
+    msg = "Transaction started, " + session.Isolation() + "."
+  case "commit":
+    run = session.Commit
+    msg = "Transaction committed."
+  case "rollback":
+    run = session.Rollback
+    msg = "Transaction rolled back."
+  case "savepoint":
+    if arg == "" {
+      arg = fmt.Sprintf("sp_%d", session.SavepointCount() + 1)
+    }
+    run = func (ctx context.Context) error { return session.Savepoint(ctx, arg) }
+    msg = "Savepoint " + arg + " created."
+  case "rollback-to", "release":
+    if arg == "" {
+      arg = session.LastSavepoint()
+    }
+    if arg == "" {
+      return "There is no savepoint."
+    }
+
+    if command == "release" {
+      run = func (ctx context.Context) error { return session.Release(ctx, arg) }
+      msg = "Savepoint " + arg + " released."
+    } else {
+      run = func (ctx context.Context) error { return session.RollbackTo(ctx, arg) }
+      msg = "Rolled back to savepoint " + arg + "."
+    }
+  case "isolation":
+    if arg == "" {
+      session.NextIsolation()
+    } else if !session.SetIsolation(arg) {
+      return "Unknown isolation level " + arg + "."
+    }
+    return "Next transactions use " + session.Isolation() + "."
+  default:
+    return command + " is undefined."
+  }
+
+  // Like the statements, a slow COMMIT or a dead connection mustn't hold the UI
+  ctx, cancel := context.WithCancel(context.Background())
+  rp.cancelQuery = cancel
+
+  c.loading.SetTextView(rp.status.tv)
+  go c.loading.Init(c.app)
+
+  go func () {
+    defer cancel()
+
+    err := run(ctx)
+
+    c.loading.Close()
+
+    c.Enqueue(func () {
+      rp.cancelQuery = nil
+      rp.UpdateTxState(c)
+
+      if c.CheckConnection(err) {
+        rp.status.SetText("The connection was lost, reconnecting. " + err.Error())
+      } else if err != nil {
+        rp.status.SetText(err.Error())
+      } else {
+        rp.status.SetText(msg)
+      }
+    })
+  }()
+
+  return ""
+}
+
+// Takes the keywords of the connected server, for the highlighting
+func (rp *RunPage) RefreshKeywords(c *Context) string {
+  if c.db == nil {
+    return "Not connected."
+  }
+
+  go func () {
+    count, err := RefreshKeywords(c.db)
+
+    c.Enqueue(func () {
+      if err != nil {
+        rp.status.SetText(err.Error())
+        return
+      }
+
+      rp.editor.modified = true
+      rp.editor.UpdateText()
+
+      rp.status.SetText(fmt.Sprintf("%d keywords loaded from the server.", count))
+    })
+  }()
+
+  return "Loading the keywords of the server..."
+}
+
+// Shows the structure of a table. By default it is the one under the cursor,
+// by name or alias, or else the first one read by the statement there.
+func (rp *RunPage) GotoTable(c *Context, table ...string) string {
+  name := strings.Join(table, " ")
+
+  if name == "" {
+    statement, pos, ok := rp.editor.StatementAtCursor()
+    if !ok {
+      return "No statement under the cursor."
+    }
+
+    q := ParseQuery(statement)
+
+    if ref, ok := q.TableAt(pos); ok {
+      name = ref.name
+    } else if ref, ok := q.Resolve(WordAt(statement, pos)); ok {
+      name = ref.name
+    } else {
+      name = q.FirstTable()
+    }
+
+    if name == "" {
+      return "No table in the statement under the cursor."
+    }
+  }
+
+  c.structPage.GoTo(name)
+  c.menuBar.Highlight("1")
+  c.selectedMenu = STRUCT_MENU
+
+  return "Showing " + name + "."
+}
+
+// Retries the connection right away, instead of waiting the backoff
+func (rp *RunPage) Reconnect(c *Context) string {
+  if c.watch == nil {
+    return "Not connected."
+  }
+
+  if !c.watch.RetryNow() {
+    return "The connection is up."
+  }
+
+  if err := c.watch.Err(); err != nil {
+    return "Reconnecting, last error: " + err.Error()
+  }
+
+  return "Reconnecting."
+}
+
+// Offers the statements that failed with the connection once it is back
+func (rp *RunPage) Reconnected(c *Context) {
+  c.RefreshMenuBar()
+  rp.UpdateTxState(c)
+
+  msg := "Reconnected."
+  if rp.lostTx {
+    msg = "Reconnected, the open transaction was lost."
+  }
+  rp.status.SetText(msg)
+  rp.lostTx = false
+
+  script, statements := rp.retryScript, rp.retryStatements
+  rp.retryScript, rp.retryStatements = "", nil
+
+  if len(statements) == 0 {
+    return
+  }
+
+  text := msg + "\n\nRun the failed statement again?\n\n" + StrClip(TrimStatement(statements[0]), 200)
+  if len(statements) > 1 {
+    text += fmt.Sprintf("\n\n(and the %d statement(s) after it)", len(statements) - 1)
+  }
+
+  c.Confirm(text, []string{"Run", "Cancel"}, func (label string) {
+    if label == "Run" {
+      rp.RunScript(c, script, statements)
+    }
+  })
+}
+
+func (rp *RunPage) UpdateTxState(c *Context) {
+  if c.session == nil {
+    rp.txState.SetText(" [gray]no session[white] ")
+    return
+  }
+
+  switch c.session.State() {
+  case TX_IDLE:
+    rp.txState.SetText(" [lightgreen]idle[white] ")
+  case TX_ACTIVE:
+    rp.txState.SetText(" [yellow]in transaction[white] ")
+  case TX_FAILED:
+    rp.txState.SetText(" [red]failed[white] ")
+  }
+}
+
+// Fetches the next page of the cursor when the table is close to its last row
+func (rp *RunPage) FetchMore(c *Context) {
+  sr := rp.tabs.Current()
+
+  if sr == nil || sr.cursor == nil || sr.cursor.Done() || rp.fetching || rp.cancelQuery != nil {
+    return
+  }
+
+  _, _, _, height := rp.table.GetInnerRect()
+  row, _ := rp.table.GetSelection()
+  offset, _ := rp.table.GetOffset()
+
+  if Max(row, offset + height) + height < rp.table.GetRowCount() {
+    return
+  }
+
+  ctx, cancel := context.WithCancel(context.Background())
+
+  rp.fetching = true
+  rp.cancelFetch = cancel
+  cursor := sr.cursor
+
+  go func () {
+    defer cancel()
+
+    result := cursor.Fetch(ctx)
+    notices := cursor.session.TakeNotices()
+    status := cursor.Status()
+
+    c.Enqueue(func () {
+      rp.fetching = false
+      rp.cancelFetch = nil
+
+      if len(notices) > 0 {
+        rp.tabs.AddNotices(sr, notices)
+      }
+
+      if result.err != nil {
+        rp.UpdateTxState(c)
+
+        if ctx.Err() == context.Canceled {
+          rp.status.SetText("Fetch cancelled, " + status + ".")
+        } else if c.CheckConnection(result.err) {
+          rp.status.SetText("The connection was lost, reconnecting. " + result.err.Error())
+        } else if sr == rp.tabs.Current() {
+          rp.status.SetText(result.err.Error())
+        }
+        return
+      }
+
+      sr.result.values = append(sr.result.values, result.values...)
+      sr.result.nulls = append(sr.result.nulls, result.nulls...)
+
+      if sr == rp.tabs.Current() && rp.diff != nil {
+        rp.ShowCurrentResult()
+      } else if sr == rp.tabs.Current() {
+        TableAppendResult(rp.table, result)
+        rp.status.SetText(status)
+      }
+    })
+  }()
+}
+
+func (rp *RunPage) Listen(c *Context, channel string, listen bool) string {
+  if c.monitor == nil {
+    return "Not connected."
+  }
+
+  var err error
+  if listen {
+    err = c.monitor.Listen(channel)
+  } else {
+    err = c.monitor.Unlisten(channel)
+  }
+
+  if err != nil {
+    return err.Error()
+  }
+
+  channels := c.monitor.Channels()
+  c.notifyPage.SetChannels(channels)
+
+  return fmt.Sprintf("Listening to %d channel(s).", len(channels))
+}
+
+// Sends a notification outside of the session, so it is delivered right away
+// even with a transaction open
+func (rp *RunPage) Notify(c *Context, channel, payload string) string {
+  if c.db == nil {
+    return "Not connected."
+  }
+
+  _, err := c.db.Exec("SELECT pg_notify($1, $2)", channel, payload)
+  if err != nil {
+    return err.Error()
+  }
+
+  return "Notification sent to " + channel + "."
+}
+
 func (rp *RunPage) SetStatus(msg string) {
   rp.status.SetText(msg)
 }
@@ -280,6 +1238,13 @@ func (rp *RunPage) Enable(item string, enable bool) string {
   switch item {
   case "numbers":
     rp.editor.EnableLineNumber(enable)
+  case "stop-on-error":
+    rp.stopOnError = enable
+  case "guard":
+    rp.guard = enable
+    if !enable {
+      return "guard disabled, protected connections still confirm."
+    }
   default:
     return item + " is undefined."
   }
@@ -291,6 +1256,28 @@ func (rp *RunPage) Enable(item string, enable bool) string {
   return item + " disabled."
 }
 
+func (rp *RunPage) Set(c *Context, item string, value int) string {
+  switch item {
+  case "page-size":
+    rp.pageSize = Max(1, value)
+    value = rp.pageSize
+  case "max-rows":
+    rp.maxRows = Max(0, value)
+    value = rp.maxRows
+  case "keepalive":
+    if c.watch == nil {
+      return "Not connected."
+    }
+    value = Max(0, value)
+    c.config.Keepalive = value
+    c.watch.SetKeepalive(time.Duration(value) * time.Second)
+  default:
+    return item + " is undefined."
+  }
+
+  return fmt.Sprintf("%s set to %d.", item, value)
+}
+
 func (rp *RunPage) Yank(s string) string {
   rp.editor.SetYanked(WrapLines(s))
   return s
@@ -313,6 +1300,37 @@ func (rp *RunPage) Import(path string) string {
   return path + " imported."
 }
 
+// Opens the dialog to copy a CSV file into a table
+func (rp *RunPage) ImportCsv(c *Context, path, table string) string {
+  if c.db == nil {
+    return "Not connected."
+  }
+
+  if c.info.readOnly {
+    return "Read only connection, nothing can be imported."
+  }
+
+  imp := NewCsvImport(path, table)
+
+  if err := imp.Resolve(context.Background(), c.db); err != nil {
+    return err.Error()
+  }
+
+  if len(imp.columns) == 0 {
+    return "Table " + table + " has no columns."
+  }
+
+  if _, err := imp.Preview(1); err != nil {
+    return err.Error()
+  }
+
+  dialog := NewImportDialog(c, imp)
+  c.mainPages.AddPage("Import", dialog.Layout(), true, true)
+  c.SetFocus(dialog.form)
+
+  return "Choose how to import " + path + "."
+}
+
 func (rp *RunPage) Export(path string) string {
   data := rp.editor.text.String()
   err := WriteFile(path, data)
@@ -324,6 +1342,89 @@ func (rp *RunPage) Export(path string) string {
   return "Exported to " + path
 }
 
+// Writes the rows of the current result tab to a file
+func (rp *RunPage) ExportResult(format, path string) string {
+  sr := rp.tabs.Current()
+
+  if sr == nil || sr.Failed() || len(sr.result.columns) == 0 {
+    return "No result to be exported."
+  }
+
+  err := ExportResult(sr.result, format, path)
+  if err != nil {
+    return err.Error()
+  }
+
+  rows := len(sr.result.values)
+  if sr.cursor != nil && !sr.cursor.Exhausted() {
+    return fmt.Sprintf("%d fetched rows exported to %s, the query has more.", rows, path)
+  }
+
+  return fmt.Sprintf("%d rows exported to %s.", rows, path)
+}
+
+// Streams a table to a file in the background, the progress goes to the status bar
+func (rp *RunPage) ExportTable(c *Context, table, path, format string) string {
+  if c.db == nil {
+    return "Not connected."
+  }
+
+  if rp.cancelExport != nil {
+    return "Another table is being exported."
+  }
+
+  ctx, cancel := context.WithCancel(context.Background())
+  rp.cancelExport = cancel
+
+  startTime := time.Now()
+  connStr := c.info.ConnString()
+
+  go func () {
+    defer cancel()
+
+    written := int64(0)
+
+    rows, err := ExportTable(ctx, connStr, table, path, format, func (bytes int64) {
+      written = bytes
+
+      c.Enqueue(func () {
+        rp.status.SetText(fmt.Sprintf("Exporting %s: %s, %s (Ctrl-Q to cancel)",
+          table, FormatSize(bytes), FormatElapsed(time.Now().Sub(startTime))))
+      })
+    })
+
+    c.Enqueue(func () {
+      rp.cancelExport = nil
+
+      if ctx.Err() == context.Canceled {
+        rp.status.SetText(fmt.Sprintf("Export of %s cancelled after %s.", table, FormatSize(written)))
+      } else if err != nil {
+        rp.status.SetText("Export of " + table + " failed: " + err.Error())
+      } else {
+        rp.status.SetText(fmt.Sprintf("%d rows of %s exported to %s in %s.",
+          rows, table, path, FormatElapsed(time.Now().Sub(startTime))))
+      }
+    })
+  }()
+
+  return "Exporting " + table + "..."
+}
+
+// Asks where the table should be exported, from the Structure page
+func (rp *RunPage) AskExportTable(c *Context, table string) {
+  c.menuBar.Highlight("0")
+  c.selectedMenu = RUN_MENU
+
+  rp.Ask(c, "export " + table + " to (csv): ", func (path string, ok bool) {
+    if !ok || path == "" {
+      rp.status.SetText("Export cancelled.")
+      return
+    }
+
+    rp.status.SetText(rp.ExportTable(c, table, path, "csv"))
+  })
+}
+
 func (rp *RunPage) TableGet(row, col string) string {
   nRow, err1 := strconv.Atoi(row)
   nCol, err2 := strconv.Atoi(col)
@@ -117,6 +117,12 @@ func (s *Selector) SetDeleteCb(cb func(int) bool) {
   s.onDelete = cb
 }
 
+func (s *Selector) SetCursor(index int) {
+  s.cursor = index
+  s.tv.Highlight(strconv.Itoa(index))
+  s.tv.ScrollToHighlight()
+}
+
 func (s *Selector) SelectedItemIndex() int {
   return s.selected 
 }
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+  "context"
+  "strings"
+  "sync"
+
+	"github.com/lib/pq"
+)
+
+type TxState byte
+
+const (
+  TX_IDLE TxState = iota
+  TX_ACTIVE
+  TX_FAILED
+)
+
+func (ts TxState) String() string {
+  switch ts {
+  case TX_IDLE:
+    return "idle"
+  case TX_ACTIVE:
+    return "in transaction"
+  case TX_FAILED:
+    return "failed"
+  }
+  return "??"
+}
+
+var isolationLevels = []string{"read committed", "repeatable read", "serializable"}
+
+// A dedicated connection, so transactions, SET commands and temp tables
+// survive between executions
+type Session struct {
+  conn *sql.Conn
+  db   *sql.DB // cancel requests go through the pool
+  pid  int     // server process of conn
+
+  // Held while conn is in use, pages of a cursor are fetched from another
+  // goroutine than the statements run
+  mutex sync.Mutex
+
+  // An open cursor must be closed before anything else runs in the session
+  cursor *ResultCursor
+
+  // Guards the state below, the UI reads it while statements run
+  stateMutex sync.Mutex
+  state      TxState
+  isolation  int // index in isolationLevels, used by Begin
+  savepoints []string
+
+  noticesMutex sync.Mutex
+  notices      []Notice
+}
+
+func OpenSession(db *sql.DB) (*Session, error) {
+  return OpenSessionContext(context.Background(), db)
+}
+
+func OpenSessionContext(ctx context.Context, db *sql.DB) (*Session, error) {
+  conn, err := db.Conn(ctx)
+
+  if err != nil {
+    return nil, err
+  }
+
+  s := &Session{conn: conn, db: db, savepoints: []string{}, notices: []Notice{}}
+
+  err = conn.Raw(func (driverConn interface{}) error {
+    pq.SetNoticeHandler(driverConn.(driver.Conn), s.addNotice)
+    return nil
+  })
+
+  if err == nil {
+    err = conn.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&s.pid)
+  }
+
+  if err != nil {
+    conn.Close()
+    return nil, err
+  }
+
+  return s, nil
+}
+
+// Runs fn on the conn. The conn never sees ctx: lib/pq closes a connection
+// whose context is cancelled, and the transaction, temp tables and settings
+// of the session would go with it. Cancelling ctx asks the server to cancel
+// the running statement instead.
+func (s *Session) use(ctx context.Context, fn func(context.Context)) {
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+
+  done := make(chan bool)
+  finished := make(chan bool)
+
+  go func () {
+    defer close(finished)
+
+    select {
+    case <-done:
+    case <-ctx.Done():
+      s.db.ExecContext(context.Background(), "SELECT pg_cancel_backend($1)", s.pid)
+    }
+  }()
+
+  fn(context.Background())
+
+  // A cancel request still on its way must not reach the next statement
+  close(done)
+  <-finished
+}
+
+func (s *Session) addNotice(err *pq.Error) {
+  s.noticesMutex.Lock()
+  s.notices = append(s.notices, NewNotice(err))
+  s.noticesMutex.Unlock()
+}
+
+// Returns the notices received since the last call
+func (s *Session) TakeNotices() []Notice {
+  s.noticesMutex.Lock()
+  defer s.noticesMutex.Unlock()
+
+  notices := s.notices
+  s.notices = []Notice{}
+
+  return notices
+}
+
+func (s *Session) Close() error {
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+
+  s.closeCursor()
+  return s.conn.Close()
+}
+
+// Pings the conn, unless it is in use: waiting for it would outlast ctx and
+// lib/pq would close it, the statement using it finds out about a drop anyway
+func (s *Session) Ping(ctx context.Context) error {
+  if !s.mutex.TryLock() {
+    return nil
+  }
+  defer s.mutex.Unlock()
+
+  return s.conn.PingContext(ctx)
+}
+
+func (s *Session) CloseCursor() {
+  s.use(context.Background(), func (ctx context.Context) {
+    s.closeCursor()
+  })
+}
+
+// Only with the conn in use
+func (s *Session) closeCursor() {
+  if s.cursor != nil {
+    s.cursor.close()
+    s.cursor = nil
+  }
+}
+
+func (s *Session) Query(ctx context.Context, query string, args ...interface{}) QueryResult {
+  var result QueryResult
+
+  s.use(ctx, func (ctx context.Context) {
+    s.closeCursor()
+    result = GetQueryResultContext(ctx, s.conn, query, args...)
+    s.Track(query, result.err)
+  })
+
+  return result
+}
+
+func (s *Session) Exec(ctx context.Context, query string, args ...interface{}) QueryResult {
+  var result QueryResult
+
+  s.use(ctx, func (ctx context.Context) {
+    s.closeCursor()
+    result = ExecStatement(ctx, s.conn, query, args...)
+    s.Track(query, result.err)
+  })
+
+  return result
+}
+
+func (s *Session) OpenCursor(ctx context.Context, query string, pageSize, maxRows int, args ...interface{}) (*ResultCursor, QueryResult) {
+  var cursor *ResultCursor
+  var result QueryResult
+
+  s.use(ctx, func (ctx context.Context) {
+    s.closeCursor()
+    cursor, result = OpenCursor(ctx, s, query, pageSize, maxRows, args...)
+    s.cursor = cursor
+
+    if result.err != nil {
+      s.Track(query, result.err)
+    }
+  })
+
+  return cursor, result
+}
+
+func (s *Session) State() TxState {
+  s.stateMutex.Lock()
+  defer s.stateMutex.Unlock()
+
+  return s.state
+}
+
+// Follows the transaction state from the statements run in the session. lib/pq
+// keeps the status the server sends after each statement to itself, and every
+// statement of the session goes through here, one at a time.
+func (s *Session) Track(statement string, err error) {
+  s.stateMutex.Lock()
+  defer s.stateMutex.Unlock()
+
+  if err != nil {
+    if s.state == TX_ACTIVE {
+      s.state = TX_FAILED
+    }
+    return
+  }
+
+  tokens, input := Tokenize(statement)
+  name := ""
+  if len(tokens) > 1 {
+    name = UnquoteIdent(tokens[len(tokens) - 1].Text(input))
+  }
+
+  switch StatementVerb(statement) {
+  case "begin", "start":
+    s.state = TX_ACTIVE
+  case "commit", "end", "abort":
+    s.state = TX_IDLE
+    s.savepoints = []string{}
+  case "prepare":
+    // PREPARE TRANSACTION ends the block, a plain PREPARE only names a statement
+    if len(tokens) > 1 && LowerWord(input, tokens[1]) == "transaction" {
+      s.state = TX_IDLE
+      s.savepoints = []string{}
+    }
+  case "rollback":
+    if IsRollbackTo(statement) {
+      s.state = TX_ACTIVE
+      s.dropSavepointsAfter(name, true)
+    } else {
+      s.state = TX_IDLE
+      s.savepoints = []string{}
+    }
+  case "savepoint":
+    s.savepoints = append(s.savepoints, name)
+  case "release":
+    s.dropSavepointsAfter(name, false)
+  }
+}
+
+func (s *Session) dropSavepointsAfter(name string, keep bool) {
+  for i := len(s.savepoints) - 1; i >= 0; i-- {
+    if s.savepoints[i] == name {
+      if keep {
+        i++
+      }
+      s.savepoints = s.savepoints[:i]
+      return
+    }
+  }
+}
+
+func (s *Session) Isolation() string {
+  s.stateMutex.Lock()
+  defer s.stateMutex.Unlock()
+
+  return isolationLevels[s.isolation]
+}
+
+// Changes the isolation level used by the next Begin
+func (s *Session) NextIsolation() string {
+  s.stateMutex.Lock()
+  s.isolation = (s.isolation + 1) % len(isolationLevels)
+  s.stateMutex.Unlock()
+
+  return s.Isolation()
+}
+
+func (s *Session) SetIsolation(level string) bool {
+  level = strings.ReplaceAll(strings.ToLower(level), "-", " ")
+
+  s.stateMutex.Lock()
+  defer s.stateMutex.Unlock()
+
+  for i, l := range isolationLevels {
+    if l == level {
+      s.isolation = i
+      return true
+    }
+  }
+
+  return false
+}
+
+func (s *Session) Begin(ctx context.Context) error {
+  return s.Exec(ctx, "BEGIN ISOLATION LEVEL " + strings.ToUpper(s.Isolation())).err
+}
+
+func (s *Session) Commit(ctx context.Context) error {
+  return s.Exec(ctx, "COMMIT").err
+}
+
+func (s *Session) Rollback(ctx context.Context) error {
+  return s.Exec(ctx, "ROLLBACK").err
+}
+
+func (s *Session) Savepoint(ctx context.Context, name string) error {
+  return s.Exec(ctx, "SAVEPOINT " + pq.QuoteIdentifier(name)).err
+}
+
+func (s *Session) RollbackTo(ctx context.Context, name string) error {
+  return s.Exec(ctx, "ROLLBACK TO SAVEPOINT " + pq.QuoteIdentifier(name)).err
+}
+
+func (s *Session) Release(ctx context.Context, name string) error {
+  return s.Exec(ctx, "RELEASE SAVEPOINT " + pq.QuoteIdentifier(name)).err
+}
+
+func (s *Session) SavepointCount() int {
+  s.stateMutex.Lock()
+  defer s.stateMutex.Unlock()
+
+  return len(s.savepoints)
+}
+
+func (s *Session) LastSavepoint() string {
+  s.stateMutex.Lock()
+  defer s.stateMutex.Unlock()
+
+  if len(s.savepoints) == 0 {
+    return ""
+  }
+  return s.savepoints[len(s.savepoints) - 1]
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+  "strings"
+  "regexp"
+  "fmt"
+  "strconv"
+)
+
+// Returns the tokens of a text, comments are left out
+func Tokenize(text string) ([]Token, []rune) {
+  input := []rune(text)
+
+  tokenizer := NewTokenizer()
+  tokenizer.SetInput(input)
+
+  tokens := []Token{}
+
+  for !tokenizer.IsEnd() {
+    token := tokenizer.NextToken()
+
+    if token.Is(READ_END) {
+      break
+    }
+
+    if !token.Is(COMMENT) {
+      tokens = append(tokens, token)
+    }
+  }
+
+  return tokens, input
+}
+
+func LowerWord(input []rune, token Token) string {
+  return strings.ToLower(token.Text(input))
+}
+
+func IsSemicolon(input []rune, token Token) bool {
+  return token.Is(PUNCT) && input[token.start] == ';'
+}
+
+// Removes the trailing semicolons and comments of a statement
+func TrimStatement(query string) string {
+  tokens, input := Tokenize(query)
+
+  for len(tokens) > 0 && IsSemicolon(input, tokens[len(tokens) - 1]) {
+    tokens = tokens[:len(tokens) - 1]
+  }
+
+  if len(tokens) == 0 {
+    return ""
+  }
+
+  last := tokens[len(tokens) - 1]
+  return string(input[:last.start + last.size])
+}
+
+// Tells if the query is a single read statement that can be declared as a
+// cursor. Queries taking row locks are left out, the locks would be held
+// while the result is browsed.
+func IsCursorQuery(query string) bool {
+  tokens, input := Tokenize(query)
+
+  if len(tokens) == 0 {
+    return false
+  }
+
+  switch LowerWord(input, tokens[0]) {
+  case "select", "with", "values", "table":
+  default:
+    return false
+  }
+
+  for i, token := range tokens {
+    switch LowerWord(input, token) {
+    case ";":
+      if i != len(tokens) - 1 {
+        return false
+      }
+    case "for":
+      if i + 1 < len(tokens) {
+        switch LowerWord(input, tokens[i + 1]) {
+        case "update", "share", "no", "key":
+          return false
+        }
+      }
+    case "update", "into", "insert", "delete":
+      return false
+    }
+  }
+
+  return true
+}
+
+// Splits a script in statements at the semicolons that are outside of strings
+// and comments. Statements made only of comments are dropped.
+func SplitStatements(script string) []string {
+  tokens, input := Tokenize(script)
+
+  statements := []string{}
+  start, count := 0, 0
+
+  for _, token := range tokens {
+    if IsSemicolon(input, token) {
+      if count > 0 {
+        statement := string(input[start: token.start + token.size])
+        statements = append(statements, strings.TrimSpace(statement))
+      }
+
+      start, count = token.start + token.size, 0
+      continue
+    }
+
+    count++
+  }
+
+  if count > 0 {
+    statements = append(statements, strings.TrimSpace(string(input[start:])))
+  }
+
+  return statements
+}
+
+// Range of the statement at a position of a script, from its first token to
+// its semicolon. The space between two statements goes with the next one, or
+// with the previous one when nothing comes after it.
+func StatementAt(script string, pos int) (int, int, bool) {
+  tokens, input := Tokenize(script)
+
+  start, end := -1, -1
+  prevStart, prevEnd := -1, -1
+
+  for _, token := range tokens {
+    if IsSemicolon(input, token) {
+      if start >= 0 {
+        end = token.start + token.size
+        if pos < end {
+          return start, end, true
+        }
+        prevStart, prevEnd = start, end
+      }
+
+      start = -1
+      continue
+    }
+
+    if start < 0 {
+      start = token.start
+    }
+    end = token.start + token.size
+  }
+
+  if start >= 0 {
+    return start, end, true
+  }
+
+  return prevStart, prevEnd, prevStart >= 0
+}
+
+var onErrorRe = regexp.MustCompile(`(?i)on_error\s*[:=]?\s*(stop|continue)`)
+
+// A script may choose what happens after a failed statement with a comment
+// like "-- on_error: continue" or "-- on_error: stop"
+func ScriptStopOnError(script string, stopByDefault bool) bool {
+  input := []rune(script)
+
+  tokenizer := NewTokenizer()
+  tokenizer.SetInput(input)
+
+  for !tokenizer.IsEnd() {
+    token := tokenizer.NextToken()
+
+    if !token.Is(COMMENT) {
+      continue
+    }
+
+    matches := onErrorRe.FindStringSubmatch(token.Text(input))
+    if len(matches) > 1 {
+      return strings.ToLower(matches[1]) == "stop"
+    }
+  }
+
+  return stopByDefault
+}
+
+// Main command of a statement, for a WITH query it is the one after the CTEs
+func StatementVerb(statement string) string {
+  tokens, input := Tokenize(statement)
+
+  i := mainVerb(tokens, input)
+  if i < 0 {
+    return ""
+  }
+
+  return LowerWord(input, tokens[i])
+}
+
+// Index of the token with the verb of the statement, the one after the CTEs
+// of a WITH. -1 for an empty statement.
+func mainVerb(tokens []Token, input []rune) int {
+  if len(tokens) == 0 {
+    return -1
+  }
+
+  if LowerWord(input, tokens[0]) != "with" {
+    return 0
+  }
+
+  depth := 0
+  for i, token := range tokens[1:] {
+    switch word := LowerWord(input, token); word {
+    case "(":
+      depth++
+    case ")":
+      depth--
+    case "select", "insert", "update", "delete", "values", "merge":
+      if depth == 0 {
+        return i + 1
+      }
+    }
+  }
+
+  return 0
+}
+
+// Token where the statement explained by the EXPLAIN at i starts, after its
+// options, -1 when there is none. Also tells if ANALYZE, which runs it, is on.
+func explainTarget(tokens []Token, input []rune, i int) (int, bool) {
+  analyze := false
+
+  for j := i + 1; j < len(tokens); j++ {
+    switch LowerWord(input, tokens[j]) {
+    case "analyze", "analyse":
+      analyze = true
+    case "verbose":
+    case "(":
+      for ; j < len(tokens) && LowerWord(input, tokens[j]) != ")"; j++ {
+        switch LowerWord(input, tokens[j]) {
+        case "analyze", "analyse":
+          analyze = true
+        }
+      }
+    default:
+      return j, analyze
+    }
+  }
+
+  return -1, analyze
+}
+
+// Tells if the statement gives rows back, otherwise it can be run as an exec
+func ReturnsRows(statement string) bool {
+  switch StatementVerb(statement) {
+  case "select", "values", "table", "show", "explain", "fetch", "call":
+    return true
+  }
+
+  tokens, input := Tokenize(statement)
+
+  depth := 0
+  for _, token := range tokens {
+    switch LowerWord(input, token) {
+    case "(":
+      depth++
+    case ")":
+      depth--
+    case "returning":
+      if depth == 0 {
+        return true
+      }
+    }
+  }
+
+  return false
+}
+
+var tagModifiers = map[string]bool{
+  "or": true, "replace": true, "unique": true, "temp": true, "temporary": true,
+  "unlogged": true, "global": true, "local": true, "recursive": true,
+  "trusted": true, "procedural": true, "default": true,
+}
+
+// Rebuilds the command tag the server sends when a statement completes,
+// like "UPDATE 42", "INSERT 0 1" or "CREATE TABLE". A negative row count
+// means the count is unknown.
+func CommandTag(statement string, rows int64) string {
+  verb := StatementVerb(statement)
+
+  count := ""
+  if rows >= 0 {
+    count = fmt.Sprintf(" %d", rows)
+  }
+
+  switch verb {
+  case "":
+    return ""
+  case "insert":
+    if rows >= 0 {
+      return "INSERT 0" + count
+    }
+    return "INSERT"
+  case "select", "values", "table":
+    return "SELECT" + count
+  case "update", "delete", "merge", "fetch", "move", "copy":
+    return strings.ToUpper(verb) + count
+  case "end":
+    return "COMMIT"
+  case "abort":
+    return "ROLLBACK"
+  case "start":
+    return "START TRANSACTION"
+  case "create", "drop", "alter":
+    tokens, input := Tokenize(statement)
+
+    words := []string{}
+    for _, token := range tokens[1:] {
+      word := LowerWord(input, token)
+      if tagModifiers[word] {
+        continue
+      }
+
+      words = append(words, word)
+      if word != "materialized" && word != "foreign" && word != "event" {
+        break
+      }
+    }
+
+    return strings.ToUpper(strings.Join(append([]string{verb}, words...), " "))
+  }
+
+  return strings.ToUpper(verb)
+}
+
+func IsRollbackTo(statement string) bool {
+  tokens, input := Tokenize(statement)
+
+  for _, token := range tokens {
+    if LowerWord(input, token) == "to" {
+      return true
+    }
+  }
+
+  return false
+}
+
+// Name of an identifier as the server sees it: folded to lower case, unless quoted
+func UnquoteIdent(ident string) string {
+  if len(ident) >= 2 && ident[0] == '"' && ident[len(ident) - 1] == '"' {
+    return strings.ReplaceAll(ident[1:len(ident) - 1], `""`, `"`)
+  }
+  return strings.ToLower(ident)
+}
+
+// Placeholder of a bind parameter, like $1 or :name
+type Param struct {
+  key        string
+  start, end int // rune positions in the statement
+}
+
+func FindParams(statement string) []Param {
+  tokens, input := Tokenize(statement)
+  params := []Param{}
+
+  for _, token := range tokens {
+    if token.Is(PARAM) {
+      params = append(params, Param{token.Text(input), token.start, token.start + token.size})
+    }
+  }
+
+  return params
+}
+
+// Replaces the named placeholders by positional ones. The keys tell which
+// placeholder feeds each position, keys[0] gives the value of $1 and so on.
+func BindParams(statement string) (string, []string) {
+  params := FindParams(statement)
+  keys := []string{}
+
+  if len(params) == 0 {
+    return statement, keys
+  }
+
+  maxPos := 0
+  for _, p := range params {
+    if p.key[0] == '$' {
+      n, _ := strconv.Atoi(p.key[1:])
+      maxPos = Max(maxPos, n)
+    }
+  }
+
+  for i := 1; i <= maxPos; i++ {
+    keys = append(keys, fmt.Sprintf("$%d", i))
+  }
+
+  input := []rune(statement)
+  result := []rune{}
+  named := map[string]int{}
+  last := 0
+
+  for _, p := range params {
+    if p.key[0] == '$' {
+      continue
+    }
+
+    pos, ok := named[p.key]
+    if !ok {
+      keys = append(keys, p.key)
+      pos = len(keys)
+      named[p.key] = pos
+    }
+
+    result = append(result, input[last:p.start]...)
+    result = append(result, []rune(fmt.Sprintf("$%d", pos))...)
+    last = p.end
+  }
+
+  result = append(result, input[last:]...)
+  return string(result), keys
+}
+
+// Placeholders of all the statements, in order and without repetitions
+func ScriptParamKeys(statements []string) []string {
+  keys := []string{}
+  seen := map[string]bool{}
+
+  for _, statement := range statements {
+    _, statementKeys := BindParams(statement)
+
+    for _, key := range statementKeys {
+      if !seen[key] {
+        seen[key] = true
+        keys = append(keys, key)
+      }
+    }
+  }
+
+  return keys
+}
+
+// Tells if the statement can run without writing anything, used to refuse
+// writes on read only connections before sending them. It only reads the
+// words, default_transaction_read_only on the server is what enforces it.
+func IsReadOnlyStatement(statement string) bool {
+  tokens, input := Tokenize(statement)
+
+  i := mainVerb(tokens, input)
+  if i < 0 {
+    return true
+  }
+
+  words := []string{}
+  for _, token := range tokens {
+    if token.Is(QUOTED_IDENT) {
+      words = append(words, UnquoteIdent(token.Text(input)))
+    } else {
+      words = append(words, LowerWord(input, token))
+    }
+  }
+
+  // Nothing may turn read write back on, set_config can do it from any query
+  for _, word := range words {
+    switch word {
+    case "write", "default_transaction_read_only", "transaction_read_only", "set_config":
+      return false
+    }
+  }
+
+  // Data-modifying CTEs, SELECT ... FOR UPDATE is only a lock
+  if words[0] == "with" {
+    for j, word := range words[:i] {
+      switch word {
+      case "insert", "delete", "merge":
+        return false
+      case "update":
+        if j == 0 || (words[j - 1] != "for" && words[j - 1] != "key") {
+          return false
+        }
+      }
+    }
+  }
+
+  switch words[i] {
+  case "select":
+    // SELECT ... INTO creates a table
+    wr := &wordReader{tokens: tokens, input: input, pos: i}
+    return !wr.find("into")
+
+  case "explain":
+    // Only EXPLAIN ANALYZE runs the statement
+    j, analyze := explainTarget(tokens, input, i)
+    if !analyze || j < 0 {
+      return true
+    }
+
+    return IsReadOnlyStatement(string(input[tokens[j].start:]))
+
+  case "values", "table", "show", "fetch", "move", "close", "declare",
+    "set", "reset", "begin", "start", "commit", "end", "rollback", "abort",
+    "savepoint", "release", "listen", "unlisten", "discard", "deallocate":
+    return true
+  }
+
+  return false
+}
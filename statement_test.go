@@ -0,0 +1,95 @@
+package main
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestIsReadOnlyStatement(t *testing.T) {
+  tests := []struct {
+    statement string
+    readOnly  bool
+  }{
+    {"select * from t", true},
+    {"select * from t for update", true},
+    {"select 1 into t2", false},
+    {"with d as (delete from t returning *) select * from d", false},
+    {"with s as (select 1) select * from s", true},
+    {"insert into t values (1)", false},
+    {"update t set a = 1", false},
+    {"show search_path", true},
+    {"set search_path = x", true},
+    {"begin", true},
+    {"explain delete from t", true},
+    {"explain analyze delete from t", false},
+    {"explain (analyze, buffers) select 1", true},
+    {"set default_transaction_read_only = off", false},
+    {"reset transaction_read_only", false},
+    {"set session characteristics as transaction read write", false},
+    {"begin read write", false},
+    {"SELECT set_config('default_transaction_read_only','off',false)", false},
+    {`select pg_catalog."set_config"('transaction_read_only', 'off', true)`, false},
+    {"create table t (a int)", false},
+  }
+
+  for _, test := range tests {
+    if readOnly := IsReadOnlyStatement(test.statement); readOnly != test.readOnly {
+      t.Errorf("%q: got %v, want %v", test.statement, readOnly, test.readOnly)
+    }
+  }
+}
+
+func TestStatementAt(t *testing.T) {
+  script := "select 1;\n\nselect ';';  \n"
+
+  tests := []struct {
+    pos       int
+    statement string
+  }{
+    {0, "select 1;"},
+    {5, "select 1;"},
+    {9, "select ';';"},
+    {12, "select ';';"},
+    {18, "select ';';"},
+    {24, "select ';';"},
+  }
+
+  for _, test := range tests {
+    start, end, ok := StatementAt(script, test.pos)
+
+    statement := ""
+    if ok {
+      statement = string([]rune(script)[start:end])
+    }
+
+    if statement != test.statement {
+      t.Errorf("at %d: got %q, want %q", test.pos, statement, test.statement)
+    }
+  }
+
+  if _, _, ok := StatementAt("  -- nothing\n", 0); ok {
+    t.Errorf("found a statement in a comment")
+  }
+}
+
+func TestBindParams(t *testing.T) {
+  tests := []struct {
+    statement string
+    bound     string
+    keys      []string
+  }{
+    {"select 1", "select 1", []string{}},
+    {"select :a, :b, :a", "select $1, $2, $1", []string{":a", ":b"}},
+    {"select $2, :x", "select $2, $3", []string{"$1", "$2", ":x"}},
+    {"select ':a', a::text, $1", "select ':a', a::text, $1", []string{"$1"}},
+    {"select :x_1 from t where y = :x_1", "select $1 from t where y = $1", []string{":x_1"}},
+  }
+
+  for _, test := range tests {
+    bound, keys := BindParams(test.statement)
+
+    if bound != test.bound || !reflect.DeepEqual(keys, test.keys) {
+      t.Errorf("%q: got %q %q, want %q %q", test.statement, bound, keys, test.bound, test.keys)
+    }
+  }
+}
@@ -27,6 +27,8 @@ type Status struct {
   onEnter  func(string)
   onCancel func()
 
+  asking bool // answers of Ask don't go to the history
+
   history *DumbHistory
 }
 
@@ -84,6 +86,32 @@ func (s *Status) SaveHistory() {
   s.history.Push(es)
 }
 
+// Prompts for a value, the previous prompt and callbacks are restored once
+// it is answered or cancelled
+func (s *Status) Ask(question string, done func(string, bool)) {
+  startWith, onEnter, onCancel := s.startWith, s.onEnter, s.onCancel
+
+  restore := func() {
+    s.startWith, s.onEnter, s.onCancel = startWith, onEnter, onCancel
+    s.asking = false
+  }
+
+  s.startWith = question
+  s.asking = true
+
+  s.onEnter = func(value string) {
+    restore()
+    done(value, true)
+  }
+
+  s.onCancel = func() {
+    restore()
+    done("", false)
+  }
+
+  s.SetMode(Prompt)
+}
+
 func (s *Status) SetEnterCb(cb func(string)) {
   s.onEnter = cb
 }
@@ -152,7 +180,7 @@ func (s *Status) HandleKeyboard(ch rune, key tcell.Key) {
         s.MoveCursorLeft()
       }
     case tcell.KeyCR:
-      if s.text.LineLen(0) != 0 {
+      if s.text.LineLen(0) != 0 && !s.asking {
         s.SaveHistory()
       }
 
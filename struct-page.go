@@ -31,6 +31,8 @@ type StructPage struct {
 
   tables []string
   cursor int
+
+  goTo string // table to select once the tables are loaded
 }
 
 func NewStructPage(c *Context) *StructPage {
@@ -114,6 +116,13 @@ func NewStructPage(c *Context) *StructPage {
         sp.SetCompType(INDEXES)
         c.SetFocus(sp.indexesTable)
 
+      } else if sp.focusedType == DATABASE && event.Rune() == 'x' {
+        if sp.selector.cursor < len(sp.tables) {
+          sp.SetCompType(MENU)
+          c.runPage.AskExportTable(c, sp.tables[sp.selector.cursor])
+        }
+        return nil
+
       } else if sp.focusedType == MENU {
         c.HandleMenuKeyInput(event)
 
@@ -138,6 +147,42 @@ func (sp *StructPage) SetTables(tables []string) {
   sp.selector.SetItems(TableNames(tables))
 }
 
+// Asks for a table to be selected the next time the tables are loaded
+func (sp *StructPage) GoTo(name string) {
+  sp.goTo = name
+}
+
+// Selects the table asked by GoTo and shows its columns and indexes
+func (sp *StructPage) SelectPending(c *Context) bool {
+  name := sp.goTo
+  sp.goTo = ""
+
+  if name == "" {
+    return false
+  }
+
+  parts := strings.Split(name, ".")
+  if len(parts) > 1 && UnquoteIdent(parts[len(parts) - 2]) != "public" {
+    return false
+  }
+  name = UnquoteIdent(parts[len(parts) - 1])
+
+  for i, table := range sp.tables {
+    if table == name {
+      sp.selector.SelectItem(i)
+      sp.selector.SetCursor(i)
+
+      sp.SetCompType(DATABASE)
+      c.SetFocus(sp.dbSelect)
+
+      go sp.QueryTableInfo(c, table)
+      return true
+    }
+  }
+
+  return false
+}
+
 func (sp *StructPage) SetCompType(t ComponentType) {
   sp.focusedType = t
 
@@ -186,9 +231,7 @@ func (sp *StructPage) QueryTableInfo(c *Context, tablename string) {
   indexes := result.values
 
   result = GetColumns(c.db, tablename)
-  result.values = ExtractColumnsData(result.values)
-
-  columns := result.values
+  columns := ExtractColumnsData(result)
 
   c.Enqueue(func () {
     TableSetData(sp.columnsTable, columnsFields[:], columns, false)
@@ -196,17 +239,21 @@ func (sp *StructPage) QueryTableInfo(c *Context, tablename string) {
   })
 }
 
-func ExtractColumnsData(values [][]string) [][]string {
-  result := make([][]string, len(values))
+func ExtractColumnsData(columns QueryResult) [][]string {
+  result := make([][]string, len(columns.values))
 
-  for i, row := range values {
+  for i, row := range columns.values {
     result[i] = make([]string, 5)
 
     result[i][0] = row[0]
 
     switch row[1] {
     case "numeric":
-      result[i][1] = fmt.Sprintf("numeric(%s, %s)", row[3], row[4])
+      if columns.IsNull(i, 3) {
+        result[i][1] = "numeric"
+      } else {
+        result[i][1] = fmt.Sprintf("numeric(%s, %s)", row[3], row[4])
+      }
     case "character varying":
       result[i][1] = fmt.Sprintf("varchar(%s)", row[2])
     case "character":
@@ -217,7 +264,7 @@ func ExtractColumnsData(values [][]string) [][]string {
       result[i][1] = row[1]
     }
 
-    if row[5] == "nil" {
+    if columns.IsNull(i, 5) {
       result[i][2] = ""
     } else if strings.HasPrefix(row[5], "nextval") {
       result[i][2] = "auto"
@@ -0,0 +1,107 @@
+package main
+
+import (
+  "bufio"
+  "context"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+
+  "github.com/jackc/pgx/v5/pgconn"
+)
+
+// Bytes written to the file between two progress reports
+const EXPORT_PROGRESS_BYTES = 1 << 20
+
+// Counts the bytes going to the file and reports them every now and then
+type progressWriter struct {
+  w        io.Writer
+  written  int64
+  reported int64
+  progress func(int64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+  n, err := pw.w.Write(p)
+  pw.written += int64(n)
+
+  if pw.written - pw.reported >= EXPORT_PROGRESS_BYTES {
+    pw.reported = pw.written
+    pw.progress(pw.written)
+  }
+
+  return n, err
+}
+
+// Size in bytes, KB, MB or GB
+func FormatSize(bytes int64) string {
+  switch {
+  case bytes >= 1 << 30:
+    return fmt.Sprintf("%.1f GB", float64(bytes) / (1 << 30))
+  case bytes >= 1 << 20:
+    return fmt.Sprintf("%.1f MB", float64(bytes) / (1 << 20))
+  case bytes >= 1 << 10:
+    return fmt.Sprintf("%.1f KB", float64(bytes) / (1 << 10))
+  }
+  return fmt.Sprintf("%d bytes", bytes)
+}
+
+// Streams a whole table to a file with COPY ... TO STDOUT, in the csv (with a
+// header), text or binary format. lib/pq can't read COPY TO, so the export
+// opens a pgconn connection of its own, like the listener does with pq.
+// progress receives the bytes written so far, the rows are given at the end.
+func ExportTable(ctx context.Context, connStr, table, path, format string, progress func(int64)) (int64, error) {
+  options := ""
+
+  switch format {
+  case "csv":
+    options = "FORMAT csv, HEADER"
+  case "text", "binary":
+    options = "FORMAT " + format
+  default:
+    return 0, errors.New("Unknown format, use csv, text or binary.")
+  }
+
+  conn, err := pgconn.Connect(ctx, connStr)
+  if err != nil {
+    return 0, err
+  }
+  defer conn.Close(context.Background())
+
+  // The name as the server quotes it, so any table it finds can be used
+  result := conn.ExecParams(ctx, "SELECT $1::regclass::text",
+    [][]byte{[]byte(table)}, nil, nil, nil).Read()
+
+  if result.Err != nil {
+    return 0, result.Err
+  }
+
+  name := string(result.Rows[0][0])
+
+  path, err = ExpandHomeDir(path)
+  if err != nil {
+    return 0, err
+  }
+
+  file, err := os.Create(path)
+  if err != nil {
+    return 0, err
+  }
+  defer file.Close()
+
+  w := bufio.NewWriter(file)
+
+  tag, err := conn.CopyTo(ctx, &progressWriter{w: w, progress: progress},
+    "COPY " + name + " TO STDOUT (" + options + ")")
+
+  if err != nil {
+    return 0, err
+  }
+
+  if err := w.Flush(); err != nil {
+    return 0, err
+  }
+
+  return tag.RowsAffected(), file.Close()
+}
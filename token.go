@@ -11,52 +11,27 @@ const (
   IDENT  TokenType = iota
   NUMBER
   STRING
+  QUOTED_IDENT
 
   TYPE
-  KEYWORD
+  KEYWORD    // reserved, can't be a name without quotes
+  UNRESERVED // keyword that can be a name
   COMMENT
 
+  OPERATOR // like =, <>, ->> or @>
+  CAST     // ::
+  PARAM    // $1 or :name
+  PUNCT    // ( ) [ ] , ; . :
+
   OTHER
   READ_END
 )
 
-  /*
-    "boolean",  "char", "character", "varchar",   "date",
-    "precision", "integer",   "int", "numeric",   "decimal",   "real",
-    "smallint",  "timestamp",
-  */
-var types [13]uint32 = [13]uint32{
-    1710517951, 2335876880, 2823553821, 4163743794, 3564297305,
-    2214008087, 3218261061, 2515107422, 1761125480, 520654156, 3604983901,
-    2174562837, 2994984227,
-  }
+const operatorChars = "+-*/<>=~!@#%^&|`?"
 
-  /*
-  hashes of:
-
-    "add",    "all",     "alter",   "from",   "any",      "replace",
-    "asc",    "between", "by",      "case",   "check",    "column",
-    "create", "left",    "default", "delete", "desc",     "where",
-    "and",    "drop",    "exec",    "exists", "foreign",  "distinct",
-    "full",   "group",   "having",  "in",     "index",    "inner",
-    "insert", "into",    "is",      "join",   "key",      "database",
-    "like",   "limit",   "not",     "null",   "or",       "order",
-    "outer",  "primary", "view",    "as",     "right",    "rownum",
-    "select", "set",     "table",   "top",    "truncate", "union",
-    "unique", "update",  "values",  "constraint", "procedure", 
-  */
-var keywords [59]uint32 = [59]uint32{
-   993596020,  321211332,  583954283,  2513272949, 740945997,  2704835779,
-   577951402,  2767062895, 1412156564, 2602907825, 3010534471, 4126724647,
-   649812317,  306900080,  2470140894, 1740784714, 2198011936, 90969176,
-   254395046,  2846199180, 3742059100, 1002329533, 1389469171, 719363213,
-   4286165820, 1605967500, 3672956252, 1094220446, 151693739,  3638454823,
-   3332609576, 798074659,  1312329493, 3374496889, 1746258028, 2707807672,
-   199741238,  853203252,  699505802,  1996966820, 1563699588, 1932267671,
-   2718680436, 1512988633, 3685020920, 1579491469, 2028154341, 1029694425,
-   297952813, 3324446467, 1251777503, 2802900028, 1111263159, 3688814324,
-   1035877158, 672109684, 877087803,  3089293914, 2800173518,
-  }
+func IsOperatorChar(r rune) bool {
+  return strings.ContainsRune(operatorChars, r)
+}
 
 func (tt TokenType) String() string {
   switch tt {
@@ -66,12 +41,24 @@ func (tt TokenType) String() string {
     return "NUMBER"
   case STRING:
     return "STRING"
+  case QUOTED_IDENT:
+    return "QUOTED_IDENT"
   case TYPE:
     return "TYPE"
   case KEYWORD:
     return "KEYWORD"
+  case UNRESERVED:
+    return "UNRESERVED"
   case COMMENT:
     return "COMMENT"
+  case OPERATOR:
+    return "OPERATOR"
+  case CAST:
+    return "CAST"
+  case PARAM:
+    return "PARAM"
+  case PUNCT:
+    return "PUNCT"
   case OTHER:
     return "OTHER"
   case READ_END:
@@ -80,23 +67,6 @@ func (tt TokenType) String() string {
   return "??";
 }
 
-func GetIdentType(name string) TokenType {
-  h := Hash(strings.ToLower(name))
-  for _, t := range types {
-    if h == t {
-      return TYPE
-    }
-  }
-
-  for _, k := range keywords {
-    if h == k {
-      return KEYWORD
-    }
-  }
-
-  return IDENT
-}
-
 type Token struct {
   ttype TokenType
   line, col int
@@ -112,6 +82,10 @@ func (t Token) Value(text *string) string {
   return (*text)[t.start: t.start + t.size]
 }
 
+func (t Token) Text(input []rune) string {
+  return string(input[t.start: t.start + t.size])
+}
+
 func (t Token) Is(ttype TokenType) bool {
   return t.ttype == ttype
 }
@@ -155,6 +129,7 @@ func (tn *Tokenizer) SetInput(input []rune) {
   tn.line = 0
   tn.col = 0
   tn.input = input
+  tn.current = Token{ttype: READ_END}
 }
 
 func (tn *Tokenizer) MakeToken(ttype TokenType, size int) Token {
@@ -237,46 +212,138 @@ func (tn *Tokenizer) ReadNumber() {
   }
 }
 
-func (tn *Tokenizer) ReadString(delim rune) {
-  c := tn.input[tn.pos]
+// Moves to the next rune, following the lines
+func (tn *Tokenizer) Advance() {
+  if tn.input[tn.pos] == '\n' {
+    tn.col = 0
+    tn.line++
+  }
+  tn.pos++
+}
 
-  if c == delim {
-    for !tn.IsEnd() {
-      tn.pos++
+// Reads a quoted text, a doubled delimiter stands for itself. Escape strings
+// (E'...') also take backslash escapes.
+func (tn *Tokenizer) ReadString(delim rune, backslash bool) {
+  tn.Advance()
 
-      if tn.pos < len(tn.input) {
-        c = tn.input[tn.pos]
-      }
+  for !tn.IsEnd() {
+    c := tn.input[tn.pos]
+    tn.Advance()
 
-      if c == delim {
-        break
+    if backslash && c == '\\' {
+      if !tn.IsEnd() {
+        tn.Advance()
       }
-
-      if c == '\n' {
-        tn.col = 0
-        tn.line++
+    } else if c == delim {
+      if tn.IsEnd() || tn.input[tn.pos] != delim {
+        return
       }
+      tn.Advance()
+    }
+  }
+}
 
-      tn.col++
+// Tag of the dollar quote starting at the current position, like $$ or
+// $body$, empty when it isn't one (a $1 parameter)
+func (tn *Tokenizer) DollarTag() string {
+  end := tn.pos + 1
+
+  for end < len(tn.input) && tn.input[end] != '$' {
+    c := tn.input[end]
+
+    if !(c == '_' || IsAlpha(c) || c > 127 || (IsDigit(c) && end > tn.pos + 1)) {
+      return ""
     }
+    end++
+  }
+
+  if end >= len(tn.input) {
+    return ""
+  }
 
-    if c == delim {
-      tn.pos += 1
+  return string(tn.input[tn.pos: end + 1])
+}
+
+// Reads a dollar quoted string, until the same tag shows up again
+func (tn *Tokenizer) ReadDollarString(tag string) {
+  delim := []rune(tag)
+  tn.pos += len(delim)
+
+  for !tn.IsEnd() {
+    if tn.input[tn.pos] == '$' && tn.pos + len(delim) <= len(tn.input) &&
+      string(tn.input[tn.pos: tn.pos + len(delim)]) == tag {
+      tn.pos += len(delim)
+      return
     }
+    tn.Advance()
   }
 }
 
 func (tn *Tokenizer) ReadIdent() {
   c := tn.input[tn.pos]
 
+  // Identifiers can have dollar signs after the first letter
   if c == '_' || IsAlpha(c) {
-    for !tn.IsEnd() && (IsAlnum(c) || c == '_') {
+    for IsAlnum(c) || c == '_' || c == '$' {
       tn.pos++
+      if tn.IsEnd() {
+        break
+      }
       c = tn.input[tn.pos]
     }
   }
 }
 
+// Reads an operator the way the server does: it stops before a comment, and
+// only ends with + or - when it has one of ~!@#%^&|`? (so a=-1 is = and -)
+func (tn *Tokenizer) ReadOperator() {
+  start := tn.pos
+
+  for !tn.IsEnd() && IsOperatorChar(tn.input[tn.pos]) {
+    if tn.pos > start && tn.pos + 1 < len(tn.input) {
+      pair := string(tn.input[tn.pos: tn.pos + 2])
+      if pair == "--" || pair == "/*" {
+        break
+      }
+    }
+    tn.pos++
+  }
+
+  if tn.pos - start > 1 && !strings.ContainsAny(string(tn.input[start: tn.pos]), "~!@#%^&|`?") {
+    for tn.pos - start > 1 && (tn.input[tn.pos - 1] == '+' || tn.input[tn.pos - 1] == '-') {
+      tn.pos--
+    }
+  }
+}
+
+// A colon before a name is a psql-like parameter, unless it follows a value,
+// as in the array slice a[lo:hi]
+func (tn *Tokenizer) IsNamedParam() bool {
+  if tn.pos + 1 >= len(tn.input) {
+    return false
+  }
+
+  next := tn.input[tn.pos + 1]
+  if next != '_' && !IsAlpha(next) {
+    return false
+  }
+
+  prev := tn.current
+  if prev.ttype == READ_END || prev.start + prev.size != tn.pos {
+    return true
+  }
+
+  switch prev.ttype {
+  case OPERATOR, CAST, COMMENT, OTHER:
+    return true
+  case PUNCT:
+    c := tn.input[prev.start]
+    return c != ')' && c != ']'
+  }
+
+  return false
+}
+
 func (tn *Tokenizer) ReadInlineComment() {
   c := tn.input[tn.pos]
   tn.pos++
@@ -290,27 +357,33 @@ func (tn *Tokenizer) ReadInlineComment() {
   }
 }
 
+// Reads a block comment, they nest like in Postgres. An unclosed one goes up
+// to the end of the input.
 func (tn *Tokenizer) ReadMultilineComment() {
-  match := string(tn.input[tn.pos: tn.pos + 2])
-
-  if match == "/*" {
-    match = string(tn.input[tn.pos + 1: tn.pos + 3])
+  depth := 0
 
-    for !tn.IsEnd() && match != "*/" {
-      if match[0] == '\n' {
-        tn.col = 0
-        tn.line++
-      }
-
-      tn.col++
-      tn.pos++
-      if tn.pos < len(tn.input) - 1 {
-        match = string(tn.input[tn.pos: tn.pos + 2])
-      }
+  for !tn.IsEnd() {
+    pair := ""
+    if tn.pos + 1 < len(tn.input) {
+      pair = string(tn.input[tn.pos: tn.pos + 2])
     }
 
-    if match == "*/" {
+    switch pair {
+    case "/*":
+      depth++
       tn.pos += 2
+      tn.col += 2
+    case "*/":
+      depth--
+      tn.pos += 2
+      tn.col += 2
+
+      if depth == 0 {
+        return
+      }
+    default:
+      tn.Advance()
+      tn.col++
     }
   }
 }
@@ -362,13 +435,90 @@ func (tn *Tokenizer) NextToken() Token {
     token.size = tn.LockPosDiff()
     tn.Commit()
 
-  } else if c == '"' || c == '\'' {
+  } else if c == '\'' {
+    tn.Lock()
+    tn.ReadString(c, false)
+    token.ttype = STRING
+    token.size = tn.LockPosDiff()
+    tn.Commit()
+
+  } else if c == '"' {
+    tn.Lock()
+    tn.ReadString(c, false)
+    token.ttype = QUOTED_IDENT
+    token.size = tn.LockPosDiff()
+    tn.Commit()
+
+  } else if strings.ContainsRune("eEbBxXnN", c) && next_c == '\'' {
+    // Escape (E''), bit (B'', X'') and national (N'') strings
     tn.Lock()
-    tn.ReadString(c)
+    tn.pos++
+    tn.ReadString(next_c, c == 'e' || c == 'E')
     token.ttype = STRING
     token.size = tn.LockPosDiff()
     tn.Commit()
 
+  } else if (c == 'u' || c == 'U') && next_c == '&' &&
+    tn.pos + 2 < len(tn.input) && (tn.input[tn.pos + 2] == '\'' || tn.input[tn.pos + 2] == '"') {
+    // Unicode escapes, U&'...' strings and U&"..." identifiers
+    quote := tn.input[tn.pos + 2]
+
+    tn.Lock()
+    tn.pos += 2
+    tn.ReadString(quote, false)
+    token.ttype = STRING
+    if quote == '"' {
+      token.ttype = QUOTED_IDENT
+    }
+    token.size = tn.LockPosDiff()
+    tn.Commit()
+
+  } else if c == '$' && tn.DollarTag() != "" {
+    tn.Lock()
+    tn.ReadDollarString(tn.DollarTag())
+    token.ttype = STRING
+    token.size = tn.LockPosDiff()
+    tn.Commit()
+
+  } else if c == '$' && IsDigit(next_c) {
+    tn.Lock()
+    tn.pos++
+    for !tn.IsEnd() && IsDigit(tn.input[tn.pos]) {
+      tn.pos++
+    }
+    token.ttype = PARAM
+    token.size = tn.LockPosDiff()
+    tn.Commit()
+
+  } else if c == ':' && next_c == ':' {
+    token.ttype = CAST
+    token.size = 2
+    tn.pos += token.size
+
+  } else if c == ':' && next_c == '=' {
+    token.ttype = OPERATOR
+    token.size = 2
+    tn.pos += token.size
+
+  } else if c == ':' && tn.IsNamedParam() {
+    tn.Lock()
+    tn.pos++
+    tn.ReadIdent()
+    token.ttype = PARAM
+    token.size = tn.LockPosDiff()
+    tn.Commit()
+
+  } else if IsOperatorChar(c) {
+    tn.Lock()
+    tn.ReadOperator()
+    token.ttype = OPERATOR
+    token.size = tn.LockPosDiff()
+    tn.Commit()
+
+  } else if strings.ContainsRune("()[],;.:", c) {
+    token.ttype = PUNCT
+    tn.pos += token.size
+
   } else if c == '_' || IsAlpha(c) {
     tn.Lock()
     tn.ReadIdent()
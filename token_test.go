@@ -0,0 +1,78 @@
+package main
+
+import (
+  "reflect"
+  "testing"
+)
+
+// Type and text of each token, comments included
+func lex(text string) []string {
+  input := []rune(text)
+
+  tokenizer := NewTokenizer()
+  tokenizer.SetInput(input)
+
+  tokens := []string{}
+
+  for !tokenizer.IsEnd() {
+    token := tokenizer.NextToken()
+
+    if token.Is(READ_END) {
+      break
+    }
+
+    tokens = append(tokens, token.ttype.String() + " " + token.Text(input))
+  }
+
+  return tokens
+}
+
+func TestTokenizer(t *testing.T) {
+  tests := []struct {
+    input  string
+    tokens []string
+  }{
+    {"select 1", []string{"KEYWORD select", "NUMBER 1"}},
+    {"a::text", []string{"IDENT a", "CAST ::", "TYPE text"}},
+    {"x >= $1", []string{"IDENT x", "OPERATOR >=", "PARAM $1"}},
+    {"'it''s'", []string{"STRING 'it''s'"}},
+    {`E'a\'b'`, []string{`STRING E'a\'b'`}},
+    {`"My Table"`, []string{`QUOTED_IDENT "My Table"`}},
+    {"$f$ a; b $f$", []string{"STRING $f$ a; b $f$"}},
+    {"-- note\n1", []string{"COMMENT -- note\n", "NUMBER 1"}},
+    {"/* a */ 1", []string{"COMMENT /* a */", "NUMBER 1"}},
+    {"/* a /* b */ c */ 1", []string{"COMMENT /* a /* b */ c */", "NUMBER 1"}},
+    {"1 /* open", []string{"NUMBER 1", "COMMENT /* open"}},
+    {"1 /*", []string{"NUMBER 1", "COMMENT /*"}},
+    {"1 /* a /* b */", []string{"NUMBER 1", "COMMENT /* a /* b */"}},
+  }
+
+  for _, test := range tests {
+    tokens := lex(test.input)
+
+    if !reflect.DeepEqual(tokens, test.tokens) {
+      t.Errorf("%q: got %q, want %q", test.input, tokens, test.tokens)
+    }
+  }
+}
+
+func TestSplitStatements(t *testing.T) {
+  tests := []struct {
+    input      string
+    statements []string
+  }{
+    {"select 1; select 2", []string{"select 1;", "select 2"}},
+    {"select ';'; select 2;", []string{"select ';';", "select 2;"}},
+    {"select 1 /* a /* b */ c ; */; select 2", []string{"select 1 /* a /* b */ c ; */;", "select 2"}},
+    {"do $$ begin; end $$;", []string{"do $$ begin; end $$;"}},
+    {"-- only a comment;\n", []string{}},
+  }
+
+  for _, test := range tests {
+    statements := SplitStatements(test.input)
+
+    if !reflect.DeepEqual(statements, test.statements) {
+      t.Errorf("%q: got %q, want %q", test.input, statements, test.statements)
+    }
+  }
+}
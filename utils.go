@@ -144,6 +144,15 @@ func CallFunction(f interface{}, values []string) (string, error) {
 
   numParams := tf.NumIn()
 
+  // The last param of a variadic function receives the remaining values
+  if tf.IsVariadic() {
+    numParams--
+
+    if tf.In(numParams).Elem().Kind() != reflect.String {
+		  return "", errors.New("Unexpected param type")
+    }
+  }
+
   if len(values) < numParams {
 		return "", errors.New("Mismatch between param numbers.")
   }
@@ -206,6 +215,12 @@ func CallFunction(f interface{}, values []string) (string, error) {
     params = append(params, param)
   }
 
+  if tf.IsVariadic() {
+    for _, value := range values[numParams:] {
+      params = append(params, reflect.ValueOf(value))
+    }
+  }
+
   out := vf.Call(params)
 
   returnValue := ""
@@ -245,20 +260,7 @@ func TableSetData(table *tview.Table, fields []string, values [][]string, showNu
   }
 
 	for r := 0; r < rows; r++ {
-    if showNumbers {
-      table.SetCell(r + 1, 0,
-        tview.NewTableCell(fmt.Sprintf("%d", r)).
-          SetTextColor(tcell.ColorYellow).
-          SetAlign(tview.AlignCenter))
-    }
-
-    cols = Min(cols, len(values[r]))
-		for c := 0; c < cols; c++ {
-			table.SetCell(r + 1, Tern(showNumbers, c + 1, c),
-				tview.NewTableCell(" " + values[r][c] + " ").
-					SetTextColor(tcell.ColorWhite).
-					SetAlign(tview.AlignLeft))
-		}
+    tableSetRow(table, r + 1, r, values[r], cols, showNumbers)
 	}
 
   if rows == 0 {
@@ -275,6 +277,23 @@ func TableSetData(table *tview.Table, fields []string, values [][]string, showNu
   }
 }
 
+func tableSetRow(table *tview.Table, at, number int, row []string, cols int, showNumbers bool) {
+  if showNumbers {
+    table.SetCell(at, 0,
+      tview.NewTableCell(fmt.Sprintf("%d", number)).
+        SetTextColor(tcell.ColorYellow).
+        SetAlign(tview.AlignCenter))
+  }
+
+  cols = Min(cols, len(row))
+  for c := 0; c < cols; c++ {
+    table.SetCell(at, Tern(showNumbers, c + 1, c),
+      tview.NewTableCell(" " + row[c] + " ").
+        SetTextColor(tcell.ColorWhite).
+        SetAlign(tview.AlignLeft))
+  }
+}
+
 func GetFormInputValue(form *tview.Form, index int) string {
   field, ok := form.GetFormItem(index).(*tview.InputField)
   if ok {